@@ -0,0 +1,125 @@
+package collections
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// An Atom is a reference to a value that many goroutines can read and
+// update safely, modeled on Clojure's atoms. It is meant for sharing
+// persistent collections: readers Load the current version without
+// locking, and writers Swap in a new version derived from the old one.
+//
+// Updates are compare-and-swap loops, so the function passed to Swap
+// may be called more than once when goroutines race, and must not have
+// side effects. Watchers are called after every update that changes
+// the value, on the goroutine that made the update.
+type Atom struct {
+	state    unsafe.Pointer // *atomState
+	watchers unsafe.Pointer // *HashMap from key to watcherFn
+}
+
+// The value of an Atom. Every update installs a new atomState, so
+// compare-and-swap on the pointer never mistakes one update for another.
+type atomState struct {
+	value interface{}
+}
+
+type watcherFn func(old interface{}, new interface{})
+
+// Factory for Atoms
+func NewAtom(value interface{}) *Atom {
+	return &Atom{
+		state:    unsafe.Pointer(&atomState{value: value}),
+		watchers: unsafe.Pointer(NewHashMap()),
+	}
+}
+
+// Returns the current value
+func (atom *Atom) Load() interface{} {
+	return atom.loadState().value
+}
+
+// Sets the value to updateFn of the current value, retrying if
+// another goroutine updates the atom in the meantime. Returns
+// the new value.
+func (atom *Atom) Swap(updateFn func(old interface{}) interface{}) interface{} {
+	for {
+		state := atom.loadState()
+		newValue := updateFn(state.value)
+		if atom.compareAndSwapState(state, newValue) {
+			return newValue
+		}
+	}
+}
+
+// Sets the value to newValue if the current value is expected, and
+// reports whether it did. Values are compared as sameValue does, so
+// collections match only if they are the same version.
+func (atom *Atom) CompareAndSet(expected interface{}, newValue interface{}) bool {
+	for {
+		state := atom.loadState()
+		if !sameValue(state.value, expected) {
+			return false
+		}
+		if atom.compareAndSwapState(state, newValue) {
+			return true
+		}
+	}
+}
+
+// Sets the value, whatever it was. Returns the old value.
+func (atom *Atom) Reset(newValue interface{}) interface{} {
+	for {
+		state := atom.loadState()
+		if atom.compareAndSwapState(state, newValue) {
+			return state.value
+		}
+	}
+}
+
+// Adds a watcher, which is called with the old and new values after
+// every update that changes the value. Adding a watcher with the key
+// of an existing watcher replaces it.
+func (atom *Atom) AddWatcher(key interface{}, watchFn func(old interface{}, new interface{})) {
+	atom.updateWatchers(func(watchers *HashMap) *HashMap {
+		return watchers.set(key, watcherFn(watchFn))
+	})
+}
+
+// Removes the watcher with the key, if there is one
+func (atom *Atom) RemoveWatcher(key interface{}) {
+	atom.updateWatchers(func(watchers *HashMap) *HashMap {
+		return watchers.remove(key)
+	})
+}
+
+func (atom *Atom) loadState() *atomState {
+	return (*atomState)(atomic.LoadPointer(&atom.state))
+}
+
+// Replaces state with a new state holding newValue, and notifies the
+// watchers if the value changed. Fails if state is no longer current.
+func (atom *Atom) compareAndSwapState(state *atomState, newValue interface{}) bool {
+	newState := &atomState{value: newValue}
+	if !atomic.CompareAndSwapPointer(&atom.state, unsafe.Pointer(state), unsafe.Pointer(newState)) {
+		return false
+	}
+	if !sameValue(state.value, newValue) {
+		watchers := (*HashMap)(atomic.LoadPointer(&atom.watchers))
+		watchers.ForEach(func(item interface{}) {
+			item.(MapEntry).Value.(watcherFn)(state.value, newValue)
+		})
+	}
+	return true
+}
+
+func (atom *Atom) updateWatchers(updateFn func(watchers *HashMap) *HashMap) {
+	for {
+		watchers := atomic.LoadPointer(&atom.watchers)
+		newWatchers := updateFn((*HashMap)(watchers))
+		if atomic.CompareAndSwapPointer(&atom.watchers, watchers, unsafe.Pointer(newWatchers)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package collections
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAtomLoadAndSwap(t *testing.T) {
+	expect := expectFor(t)
+	atom := NewAtom(NewHashMap())
+	result := atom.Swap(func(old interface{}) interface{} {
+		return old.(Map).Set("a", 1)
+	})
+	expect(atom.Load()).ToBe(result)
+	val, _ := atom.Load().(Map).Get("a")
+	expect(val).ToBe(1)
+}
+
+func TestAtomCompareAndSet(t *testing.T) {
+	expect := expectFor(t)
+	first := NewHashMap().Set("a", 1)
+	second := first.Set("b", 2)
+	atom := NewAtom(first)
+	expect(atom.CompareAndSet(second, first)).ToBe(false)
+	expect(atom.Load()).ToBe(first)
+	expect(atom.CompareAndSet(first, second)).ToBe(true)
+	expect(atom.Load()).ToBe(second)
+
+	expect(atom.Reset(first)).ToBe(second)
+	expect(atom.Load()).ToBe(first)
+}
+
+func TestAtomWatchers(t *testing.T) {
+	expect := expectFor(t)
+	atom := NewAtom(0)
+	changes := [][2]interface{}{}
+	atom.AddWatcher("log", func(old interface{}, new interface{}) {
+		changes = append(changes, [2]interface{}{old, new})
+	})
+	atom.Swap(func(old interface{}) interface{} { return old.(int) + 1 })
+	atom.Reset(1)
+	atom.CompareAndSet(1, 5)
+	atom.CompareAndSet(1, 7)
+	expect(changes).ToDeepEqual([][2]interface{}{{0, 1}, {1, 5}})
+
+	atom.RemoveWatcher("log")
+	atom.Reset(6)
+	expect(len(changes)).ToBe(2)
+}
+
+func TestAtomConcurrentUpdates(t *testing.T) {
+	expect := expectFor(t)
+	atom := NewAtom(NewHashMap())
+	watched := 0
+	var watchedLock sync.Mutex
+	atom.AddWatcher("count", func(old interface{}, new interface{}) {
+		watchedLock.Lock()
+		watched++
+		watchedLock.Unlock()
+	})
+
+	const goroutines = 16
+	const updates = 200
+	var wait sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wait.Add(1)
+		go func(g int) {
+			defer wait.Done()
+			for i := 0; i < updates; i++ {
+				key := g*updates + i
+				atom.Swap(func(old interface{}) interface{} {
+					return old.(Map).Set(key, g).MergeWith(NewHashMap().Set("total", 1), sumCounts)
+				})
+				// Readers see a consistent version at all times
+				snapshot := atom.Load().(Map)
+				total, _ := snapshot.Get("total")
+				if snapshot.Size() != total.(int)+1 {
+					t.Errorf("saw a map of size %d with a total of %d", snapshot.Size(), total)
+				}
+			}
+		}(g)
+	}
+	wait.Wait()
+
+	result := atom.Load().(*HashMap)
+	total, _ := result.Get("total")
+	expect(total).ToBe(goroutines * updates)
+	expect(result.Size()).ToBe(goroutines*updates + 1)
+	expect(watched).ToBe(goroutines * updates)
+}
@@ -0,0 +1,225 @@
+package collections
+
+// A BiMap is an immutable one-to-one Map: no two keys have the same
+// value. Values as well as keys can be looked up, and Inverse returns
+// the BiMap from values to keys in O(1).
+//
+// A BiMap is a pair of HashMaps, one from keys to values and one from
+// values to keys, which are always updated together. Values are hashed
+// like keys, so they must be hashable too.
+//
+// Setting a key to a value that already belongs to another key would
+// break the one-to-one rule, so Set panics with ErrDuplicateValue.
+// ForceSet instead evicts the other key.
+type BiMap struct {
+	forward  *HashMap
+	backward *HashMap
+}
+
+var _ Map = (*BiMap)(nil)
+
+// Factory for BiMaps
+func NewBiMap() *BiMap {
+	return &BiMap{
+		forward:  NewHashMap(),
+		backward: NewHashMap(),
+	}
+}
+
+// Returns the BiMap from the values of this map to their keys.
+// This is O(1), and Inverse().Inverse() is the original map.
+func (biMap *BiMap) Inverse() *BiMap {
+	return &BiMap{
+		forward:  biMap.backward,
+		backward: biMap.forward,
+	}
+}
+
+// Map Methods
+
+// The number of entries in the map
+func (biMap *BiMap) Size() int {
+	return biMap.forward.size
+}
+
+func (biMap *BiMap) Contains(key interface{}) bool {
+	return biMap.forward.Contains(key)
+}
+
+// Returns true if some key has the value
+func (biMap *BiMap) ContainsValue(value interface{}) bool {
+	return biMap.backward.Contains(value)
+}
+
+func (biMap *BiMap) Get(key interface{}) (interface{}, bool) {
+	return biMap.forward.Get(key)
+}
+
+// Returns the key with the value
+func (biMap *BiMap) GetKey(value interface{}) (interface{}, bool) {
+	return biMap.backward.Get(value)
+}
+
+// Sets the key to the value. Panics with ErrDuplicateValue if the
+// value already belongs to a different key.
+func (biMap *BiMap) Set(key interface{}, value interface{}) Map {
+	return biMap.set(key, value, false)
+}
+
+// Sets the key to the value. If the value already belongs to a
+// different key, that key is removed.
+func (biMap *BiMap) ForceSet(key interface{}, value interface{}) *BiMap {
+	return biMap.set(key, value, true)
+}
+
+func (biMap *BiMap) set(key interface{}, value interface{}, force bool) *BiMap {
+	forward, backward := biMap.forward, biMap.backward
+	if otherKey, found := backward.Get(value); found {
+		if keysEqual(otherKey, key) {
+			return biMap
+		}
+		if !force {
+			panic(ErrDuplicateValue)
+		}
+		forward = forward.remove(otherKey)
+	}
+	if oldValue, found := forward.Get(key); found {
+		backward = backward.remove(oldValue)
+	}
+	return &BiMap{
+		forward:  forward.set(key, value),
+		backward: backward.set(value, key),
+	}
+}
+
+func (biMap *BiMap) Remove(key interface{}) Map {
+	value, found := biMap.forward.Get(key)
+	if !found {
+		return biMap
+	}
+	return &BiMap{
+		forward:  biMap.forward.remove(key),
+		backward: biMap.backward.remove(value),
+	}
+}
+
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value from other wins. Panics with
+// ErrDuplicateValue if the result would not be one-to-one.
+func (biMap *BiMap) Merge(other Map) Map {
+	return biMap.MergeWith(other, func(key interface{}, left interface{}, right interface{}) interface{} {
+		return right
+	})
+}
+
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value is the result of calling resolve with
+// the key, the value in this map and the value in other. Panics with
+// ErrDuplicateValue if the result would not be one-to-one.
+func (biMap *BiMap) MergeWith(other Map, resolve func(key interface{}, left interface{}, right interface{}) interface{}) Map {
+	forward := biMap.forward.MergeWith(other, resolve).(*HashMap)
+	if forward == biMap.forward {
+		return biMap
+	}
+	// Only the result needs to be one-to-one, so the old values of
+	// the merged keys are all removed before any new value is added
+	backward := biMap.backward
+	other.ForEach(func(item interface{}) {
+		if oldValue, found := biMap.forward.Get(item.(MapEntry).Key); found {
+			backward = backward.remove(oldValue)
+		}
+	})
+	other.ForEach(func(item interface{}) {
+		key := item.(MapEntry).Key
+		value, _ := forward.Get(key)
+		if otherKey, found := backward.Get(value); found && !keysEqual(otherKey, key) {
+			panic(ErrDuplicateValue)
+		}
+		backward = backward.set(value, key)
+	})
+	return &BiMap{
+		forward:  forward,
+		backward: backward,
+	}
+}
+
+// Returns a lazy Iterable over the keys of the map
+func (biMap *BiMap) Keys() Iterable {
+	return biMap.forward.Keys()
+}
+
+// Returns a lazy Iterable over the values of the map
+func (biMap *BiMap) Values() Iterable {
+	return biMap.forward.Values()
+}
+
+// Returns the keys of the map as a Set in O(1). The values are
+// Inverse().KeySet().
+func (biMap *BiMap) KeySet() Set {
+	return biMap.forward.KeySet()
+}
+
+// Iterable Methods
+
+func (biMap *BiMap) Iterator() Iterator {
+	return biMap.forward.Iterator()
+}
+
+func (biMap *BiMap) ForEach(iterFn func(interface{})) {
+	forEachHelper(biMap, iterFn)
+}
+
+func (biMap *BiMap) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(biMap, mapFn)
+}
+
+func (biMap *BiMap) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(biMap, filterFn)
+}
+
+func (biMap *BiMap) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(biMap, initialValue, reducerFn)
+}
+
+func (biMap *BiMap) ToSlice() []interface{} {
+	return toSliceHelper(biMap)
+}
+
+func (biMap *BiMap) ToVector() *Vector {
+	return toVectorHelper(biMap)
+}
+
+func (biMap *BiMap) Take(count int) Iterable {
+	return takeHelper(biMap, count)
+}
+
+func (biMap *BiMap) Skip(count int) Iterable {
+	return skipHelper(biMap, count)
+}
+
+func (biMap *BiMap) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(biMap, matchFn)
+}
+
+func (biMap *BiMap) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(biMap, groupFn)
+}
+
+func (biMap *BiMap) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(biMap, groupFn, initialValue, reducerFn)
+}
+
+func (biMap *BiMap) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(biMap, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Map with the same keys and values
+func (biMap *BiMap) Equals(other interface{}) bool {
+	return equalMaps(biMap, other)
+}
+
+func (biMap *BiMap) Hash() uint64 {
+	return hashOfMap(biMap)
+}
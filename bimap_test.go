@@ -0,0 +1,128 @@
+package collections
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBiMapIsAMap(t *testing.T) {
+	expect := expectFor(t)
+	expect(NewBiMap()).ToBeAssignableTo(reflect.TypeOf((*Map)(nil)).Elem())
+}
+
+func TestBiMapLookupsBothWays(t *testing.T) {
+	expect := expectFor(t)
+	m := NewBiMap().Set(1, "one").Set(2, "two").(*BiMap)
+	val, found := m.Get(1)
+	expect(val).ToBe("one")
+	expect(found).ToBe(true)
+	key, found := m.GetKey("two")
+	expect(key).ToBe(2)
+	expect(found).ToBe(true)
+	_, found = m.GetKey("three")
+	expect(found).ToBe(false)
+	expect(m.ContainsValue("one")).ToBe(true)
+	expect(m.Size()).ToBe(2)
+}
+
+func TestBiMapInverse(t *testing.T) {
+	expect := expectFor(t)
+	m := NewBiMap().Set(1, "one").Set(2, "two").(*BiMap)
+	inverse := m.Inverse()
+	val, _ := inverse.Get("one")
+	expect(val).ToBe(1)
+	expect(inverse.Size()).ToBe(2)
+	expect(inverse.Inverse().forward).ToBe(m.forward)
+
+	// Updates through the inverse keep both sides in step
+	updated := inverse.Set("three", 3).(*BiMap).Inverse()
+	val, _ = updated.Get(3)
+	expect(val).ToBe("three")
+	expect(m.Contains(3)).ToBe(false)
+}
+
+func TestBiMapSetReplacesOldValue(t *testing.T) {
+	expect := expectFor(t)
+	m := NewBiMap().Set(1, "one").Set(1, "uno").(*BiMap)
+	val, _ := m.Get(1)
+	expect(val).ToBe("uno")
+	expect(m.ContainsValue("one")).ToBe(false)
+	expect(m.Size()).ToBe(1)
+	expect(m.Set(1, "uno")).ToBe(m)
+}
+
+func TestBiMapDuplicateValues(t *testing.T) {
+	expect := expectFor(t)
+	m := NewBiMap().Set(1, "one").Set(2, "two").(*BiMap)
+	expect(func() { m.Set(3, "one") }).ToPanicWith(ErrDuplicateValue)
+	expect(func() { m.Set(2, "one") }).ToPanicWith(ErrDuplicateValue)
+
+	forced := m.ForceSet(3, "one")
+	expect(forced.Contains(1)).ToBe(false)
+	key, _ := forced.GetKey("one")
+	expect(key).ToBe(3)
+	expect(forced.Size()).ToBe(2)
+
+	forced = m.ForceSet(2, "one")
+	expect(forced.Size()).ToBe(1)
+	expect(forced.ContainsValue("two")).ToBe(false)
+	key, _ = forced.GetKey("one")
+	expect(key).ToBe(2)
+}
+
+func TestBiMapRemove(t *testing.T) {
+	expect := expectFor(t)
+	m := NewBiMap().Set(1, "one").Set(2, "two")
+	removed := m.Remove(1).(*BiMap)
+	expect(removed.Contains(1)).ToBe(false)
+	expect(removed.ContainsValue("one")).ToBe(false)
+	expect(removed.Size()).ToBe(1)
+	expect(m.Remove(5)).ToBe(m)
+}
+
+func TestBiMapStaysOneToOne(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	m := NewBiMap()
+	for i := 0; i < 2000; i++ {
+		key, value := random.rand.Intn(50), random.rand.Intn(50)
+		if random.rand.Intn(4) == 0 {
+			m = m.Remove(key).(*BiMap)
+		} else {
+			m = m.ForceSet(key, value)
+		}
+	}
+	expect(m.forward.Size()).ToBe(m.backward.Size())
+	m.ForEach(func(item interface{}) {
+		entry := item.(MapEntry)
+		key, _ := m.GetKey(entry.Value)
+		expect(key).ToBe(entry.Key)
+	})
+}
+
+func TestBiMapMerge(t *testing.T) {
+	expect := expectFor(t)
+	m := NewBiMap().Set(1, "one")
+	merged := m.Merge(NewHashMap().Set(1, "uno").Set(2, "two")).(*BiMap)
+	key, _ := merged.GetKey("uno")
+	expect(key).ToBe(1)
+	expect(merged.ContainsValue("one")).ToBe(false)
+	expect(merged.Size()).ToBe(2)
+	expect(func() { m.Merge(NewHashMap().Set(2, "one")) }).ToPanicWith(ErrDuplicateValue)
+}
+
+func TestBiMapMergeSwappingValues(t *testing.T) {
+	expect := expectFor(t)
+	m := NewBiMap().Set(1, "a").Set(2, "b")
+	swapped := m.Merge(NewBiMap().Set(1, "b").Set(2, "a")).(*BiMap)
+	expect(swapped.Size()).ToBe(2)
+	value, _ := swapped.Get(1)
+	expect(value).ToBe("b")
+	key, _ := swapped.GetKey("b")
+	expect(key).ToBe(1)
+	key, _ = swapped.GetKey("a")
+	expect(key).ToBe(2)
+	expect(swapped.Inverse().Size()).ToBe(2)
+	expect(func() { m.Merge(NewHashMap().Set(1, "b")) }).ToPanicWith(ErrDuplicateValue)
+	expect(func() { m.Merge(NewHashMap().Set(3, "c").Set(4, "c")) }).ToPanicWith(ErrDuplicateValue)
+}
@@ -0,0 +1,275 @@
+package collections
+
+// A Deque is an immutable double-ended queue, implemented as a 2-3
+// finger tree (see fingertree.go). Pushing, popping and peeking at
+// either end are amortized O(1), even when many versions are derived
+// from the same Deque, while Get, Update, SplitAt, Slice and Concat are
+// O(log n), because every node of the tree knows how many elements
+// are below it.
+//
+// Use a Deque when elements come and go at both ends, or when
+// sequences are often split apart and joined back together. For
+// mostly indexed access a Vector is faster.
+type Deque struct {
+	tree *fingerTree
+}
+
+var _ Sequence = (*Deque)(nil)
+
+var emptyDeque = &Deque{}
+
+// Factory for Deques
+func NewDeque(values ...interface{}) *Deque {
+	var tree *fingerTree
+	for _, value := range values {
+		tree = pushFingerBack(tree, value)
+	}
+	return &Deque{
+		tree: tree,
+	}
+}
+
+// Deque Methods
+
+// Returns a new Deque with the item added at the front
+func (deque *Deque) PushFront(item interface{}) *Deque {
+	return &Deque{
+		tree: pushFingerFront(deque.tree, item),
+	}
+}
+
+// Returns a new Deque with the item added at the back
+func (deque *Deque) PushBack(item interface{}) *Deque {
+	return &Deque{
+		tree: pushFingerBack(deque.tree, item),
+	}
+}
+
+// Returns a new Deque with the front item removed, along with
+// that item. If the Deque is empty the boolean third return value
+// will be false and the popped value will be nil.
+func (deque *Deque) PopFront() (*Deque, interface{}, bool) {
+	if deque.tree == nil {
+		return deque, nil, false
+	}
+	item, tree := popFingerFront(deque.tree)
+	return &Deque{tree: tree}, item, true
+}
+
+// Returns a new Deque with the back item removed, along with
+// that item. If the Deque is empty the boolean third return value
+// will be false and the popped value will be nil.
+func (deque *Deque) PopBack() (*Deque, interface{}, bool) {
+	if deque.tree == nil {
+		return deque, nil, false
+	}
+	item, tree := popFingerBack(deque.tree)
+	return &Deque{tree: tree}, item, true
+}
+
+// Returns the front item of the Deque. If the Deque is empty
+// the return value will be nil and the added boolean flag will be false.
+func (deque *Deque) PeekFront() (interface{}, bool) {
+	if deque.tree == nil {
+		return nil, false
+	}
+	return peekFingerFront(deque.tree), true
+}
+
+// Returns the back item of the Deque. If the Deque is empty
+// the return value will be nil and the added boolean flag will be false.
+func (deque *Deque) PeekBack() (interface{}, bool) {
+	if deque.tree == nil {
+		return nil, false
+	}
+	return peekFingerBack(deque.tree), true
+}
+
+// Returns a Deque with the first index elements, and a Deque with
+// the rest. Panics with ErrIndexOutOfRange unless 0 <= index <= Size().
+func (deque *Deque) SplitAt(index int) (*Deque, *Deque) {
+	if index < 0 || index > deque.Size() {
+		panic(ErrIndexOutOfRange)
+	}
+	if index == 0 {
+		return emptyDeque, deque
+	}
+	if index == deque.Size() {
+		return deque, emptyDeque
+	}
+	before, item, _, after := splitFingerTree(deque.tree, index)
+	return &Deque{tree: before}, &Deque{tree: pushFingerFront(after, item)}
+}
+
+// Returns a Deque with the elements of this Deque followed by
+// those of other
+func (deque *Deque) Concat(other *Deque) *Deque {
+	if other.tree == nil {
+		return deque
+	}
+	if deque.tree == nil {
+		return other
+	}
+	return &Deque{
+		tree: concatFingerTrees(deque.tree, nil, other.tree),
+	}
+}
+
+// Sequence Methods
+
+func (deque *Deque) Size() int {
+	return fingerTreeSize(deque.tree)
+}
+
+func (deque *Deque) Get(index int) interface{} {
+	if index < 0 || index >= deque.Size() {
+		panic(ErrIndexOutOfRange)
+	}
+	return getFingerTree(deque.tree, index)
+}
+
+func (deque *Deque) Update(index int, value interface{}) Sequence {
+	if index < 0 || index >= deque.Size() {
+		panic(ErrIndexOutOfRange)
+	}
+	return &Deque{
+		tree: updateFingerTree(deque.tree, index, value),
+	}
+}
+
+func (deque *Deque) Append(value interface{}) Sequence {
+	return deque.PushBack(value)
+}
+
+func (deque *Deque) Prepend(value interface{}) Sequence {
+	return deque.PushFront(value)
+}
+
+func (deque *Deque) Slice(start int, end int) Sequence {
+	if start < 0 || end > deque.Size() || start > end {
+		panic(ErrIndexOutOfRange)
+	}
+	before, _ := deque.SplitAt(end)
+	_, slice := before.SplitAt(start)
+	return slice
+}
+
+// Iterable Methods
+
+func (deque *Deque) Iterator() Iterator {
+	iterator := &DequeIterator{}
+	if deque.tree != nil {
+		iterator.pending = []interface{}{deque.tree}
+	}
+	return iterator
+}
+
+func (deque *Deque) ForEach(iterFn func(interface{})) {
+	forEachHelper(deque, iterFn)
+}
+
+func (deque *Deque) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(deque, mapFn)
+}
+
+func (deque *Deque) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(deque, filterFn)
+}
+
+func (deque *Deque) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(deque, initialValue, reducerFn)
+}
+
+func (deque *Deque) ToSlice() []interface{} {
+	return toSliceHelper(deque)
+}
+
+func (deque *Deque) ToVector() *Vector {
+	return toVectorHelper(deque)
+}
+
+func (deque *Deque) Take(count int) Iterable {
+	return takeHelper(deque, count)
+}
+
+func (deque *Deque) Skip(count int) Iterable {
+	return skipHelper(deque, count)
+}
+
+func (deque *Deque) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(deque, matchFn)
+}
+
+func (deque *Deque) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(deque, groupFn)
+}
+
+func (deque *Deque) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(deque, groupFn, initialValue, reducerFn)
+}
+
+func (deque *Deque) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(deque, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Sequence with the same elements in the same order
+func (deque *Deque) Equals(other interface{}) bool {
+	return equalSequences(deque, other)
+}
+
+func (deque *Deque) Hash() uint64 {
+	return hashOfSequence(deque)
+}
+
+// An Iterator over the elements of a Deque from front to back. It
+// keeps a stack of the trees, nodes and elements still to visit, with
+// the next one on top, so each step is amortized O(1).
+type DequeIterator struct {
+	pending []interface{}
+	current interface{}
+	valid   bool
+}
+
+func (iterator *DequeIterator) MoveNext() bool {
+	for len(iterator.pending) > 0 {
+		last := len(iterator.pending) - 1
+		item := iterator.pending[last]
+		iterator.pending = iterator.pending[:last]
+		switch item := item.(type) {
+		case *fingerTree:
+			if item.isSingle() {
+				iterator.pending = append(iterator.pending, item.single)
+				continue
+			}
+			iterator.pushReversed(item.back)
+			if middle := item.middle.force(); middle != nil {
+				iterator.pending = append(iterator.pending, middle)
+			}
+			iterator.pushReversed(item.front)
+		case *fingerNode:
+			iterator.pushReversed(item.items)
+		default:
+			iterator.current = item
+			iterator.valid = true
+			return true
+		}
+	}
+	iterator.current = nil
+	iterator.valid = false
+	return false
+}
+
+func (iterator *DequeIterator) pushReversed(items []interface{}) {
+	for i := len(items) - 1; i >= 0; i-- {
+		iterator.pending = append(iterator.pending, items[i])
+	}
+}
+
+func (iterator *DequeIterator) Current() interface{} {
+	if !iterator.valid {
+		panic(ErrIterationOutOfRange)
+	}
+	return iterator.current
+}
@@ -0,0 +1,327 @@
+package collections
+
+import (
+	"sync"
+	"testing"
+)
+
+// Checks the shape of a deque's finger tree: digits hold one to four
+// items, nodes two or three, every item at a level is as deep as the
+// others, and cached sizes are right
+func expectFingerTreeInvariants(t *testing.T, deque *Deque) {
+	t.Helper()
+	var checkItem func(item interface{}, depth int) int
+	checkItem = func(item interface{}, depth int) int {
+		if depth == 0 {
+			if _, ok := item.(*fingerNode); ok {
+				t.Fatalf("node where an element belongs")
+			}
+			return 1
+		}
+		node, ok := item.(*fingerNode)
+		if !ok || len(node.items) < 2 || len(node.items) > 3 {
+			t.Fatalf("bad node at depth %d: %v", depth, item)
+		}
+		size := 0
+		for _, child := range node.items {
+			size += checkItem(child, depth-1)
+		}
+		if size != node.size {
+			t.Fatalf("node size is %d, but it holds %d", node.size, size)
+		}
+		return size
+	}
+	var checkTree func(tree *fingerTree, depth int) int
+	checkTree = func(tree *fingerTree, depth int) int {
+		if tree == nil {
+			return 0
+		}
+		size := 0
+		if tree.isSingle() {
+			if tree.middle != nil || tree.back != nil {
+				t.Fatalf("single tree with digits")
+			}
+			size = checkItem(tree.single, depth)
+		} else {
+			for _, digit := range [][]interface{}{tree.front, tree.back} {
+				if len(digit) < 1 || len(digit) > 4 {
+					t.Fatalf("digit with %d items", len(digit))
+				}
+				for _, item := range digit {
+					size += checkItem(item, depth)
+				}
+			}
+			middleSize := checkTree(tree.middle.force(), depth+1)
+			if middleSize != tree.middle.treeSize() {
+				t.Fatalf("lazy middle tree size is %d, but it holds %d", tree.middle.treeSize(), middleSize)
+			}
+			size += middleSize
+		}
+		if size != tree.size {
+			t.Fatalf("tree size is %d, but it holds %d", tree.size, size)
+		}
+		return size
+	}
+	checkTree(deque.tree, 0)
+}
+
+func TestEmptyDeque(t *testing.T) {
+	expect := expectFor(t)
+	deque := NewDeque()
+	expect(deque.Size()).ToBe(0)
+	expect(deque.ToSlice()).ToDeepEqual([]interface{}{})
+	_, found := deque.PeekFront()
+	expect(found).ToBe(false)
+	_, found = deque.PeekBack()
+	expect(found).ToBe(false)
+	popped, value, found := deque.PopFront()
+	expect(popped).ToBe(deque)
+	expect(value).ToBe(nil)
+	expect(found).ToBe(false)
+	popped, value, found = deque.PopBack()
+	expect(popped).ToBe(deque)
+	expect(value).ToBe(nil)
+	expect(found).ToBe(false)
+}
+
+func TestDequePushAndPopBothEnds(t *testing.T) {
+	expect := expectFor(t)
+	deque := NewDeque()
+	for i := 0; i < 1000; i++ {
+		deque = deque.PushBack(i).PushFront(-i - 1)
+	}
+	expect(deque.Size()).ToBe(2000)
+	front, _ := deque.PeekFront()
+	back, _ := deque.PeekBack()
+	expect(front).ToBe(-1000)
+	expect(back).ToBe(999)
+	for i := 999; i >= 0; i-- {
+		var first, last interface{}
+		deque, first, _ = deque.PopFront()
+		deque, last, _ = deque.PopBack()
+		expect(first).ToBe(-i - 1)
+		expect(last).ToBe(i)
+	}
+	expect(deque.Size()).ToBe(0)
+}
+
+func TestDequeIsImmutable(t *testing.T) {
+	deques := []*Deque{}
+	deque := NewDeque()
+	for i := 0; i < 500; i++ {
+		deques = append(deques, deque)
+		deque = deque.PushBack(i)
+	}
+	model := rangeSlice(500)
+	for i, deque := range deques {
+		expectFingerTreeInvariants(t, deque)
+		expectSequenceMatches(t, deque, model[:i])
+	}
+}
+
+func TestDequeGetAndUpdate(t *testing.T) {
+	expect := expectFor(t)
+	model := rangeSlice(1000)
+	original := NewDeque(model...)
+	expectFingerTreeInvariants(t, original)
+	expectSequenceMatches(t, original, model)
+	updated := original.Update(500, "middle").Update(0, "first").Update(999, "last")
+	expect(updated.Get(500)).ToBe("middle")
+	expect(updated.Get(0)).ToBe("first")
+	expect(updated.Get(999)).ToBe("last")
+	expectSequenceMatches(t, original, model)
+	expect(func() { original.Get(1000) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { original.Get(-1) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { original.Update(1000, 0) }).ToPanicWith(ErrIndexOutOfRange)
+}
+
+func TestDequeSplitAt(t *testing.T) {
+	expect := expectFor(t)
+	for _, size := range []int{0, 1, 2, 5, 9, 100} {
+		model := rangeSlice(size)
+		deque := NewDeque(model...)
+		for index := 0; index <= size; index++ {
+			before, after := deque.SplitAt(index)
+			expectFingerTreeInvariants(t, before)
+			expectSequenceMatches(t, before, model[:index])
+			expectFingerTreeInvariants(t, after)
+			expectSequenceMatches(t, after, model[index:])
+		}
+		expect(func() { deque.SplitAt(size + 1) }).ToPanicWith(ErrIndexOutOfRange)
+		expect(func() { deque.SplitAt(-1) }).ToPanicWith(ErrIndexOutOfRange)
+	}
+}
+
+func TestDequeConcat(t *testing.T) {
+	for _, leftSize := range []int{0, 1, 4, 9, 100} {
+		for _, rightSize := range []int{0, 1, 4, 9, 100} {
+			left := rangeSlice(leftSize)
+			right := make([]interface{}, rightSize)
+			for i := range right {
+				right[i] = -i
+			}
+			deque := NewDeque(left...).Concat(NewDeque(right...))
+			expectFingerTreeInvariants(t, deque)
+			expectSequenceMatches(t, deque, concatSlices(left, right))
+		}
+	}
+}
+
+func TestDequeSlice(t *testing.T) {
+	expect := expectFor(t)
+	model := rangeSlice(100)
+	deque := NewDeque(model...)
+	slice := deque.Slice(10, 90).(*Deque)
+	expectFingerTreeInvariants(t, slice)
+	expectSequenceMatches(t, slice, model[10:90])
+	expectSequenceMatches(t, deque.Slice(50, 50), model[50:50])
+	expect(func() { deque.Slice(-1, 5) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { deque.Slice(5, 101) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { deque.Slice(6, 5) }).ToPanicWith(ErrIndexOutOfRange)
+}
+
+func TestDequeIsASequence(t *testing.T) {
+	expect := expectFor(t)
+	var sequence Sequence = NewDeque(2, 3)
+	sequence = sequence.Prepend(1).Append(4)
+	expect(sequence.ToSlice()).ToDeepEqual([]interface{}{1, 2, 3, 4})
+	expect(sequence.Equals(NewVector(1, 2, 3, 4))).ToBe(true)
+	expect(sequence.Hash()).ToBe(NewVector(1, 2, 3, 4).Hash())
+	expect(sequence.ToVector().ToSlice()).ToDeepEqual([]interface{}{1, 2, 3, 4})
+}
+
+func TestDequeIteratorOutOfRange(t *testing.T) {
+	expect := expectFor(t)
+	iterator := NewDeque(nil).Iterator()
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+	expect(iterator.MoveNext()).ToBe(true)
+	expect(iterator.Current()).ToBe(nil)
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+}
+
+// Pushing onto a version whose front digit is full moves a node into
+// the middle tree, which must be put off rather than done on every push
+func TestDequePushOntoSharedVersionIsLazy(t *testing.T) {
+	expect := expectFor(t)
+	shared := NewDeque()
+	for i := 0; i < 1000 || len(shared.tree.front) < 4; i++ {
+		shared = shared.PushFront(i)
+	}
+	model := shared.ToSlice()
+	for i := 0; i < 100; i++ {
+		pushed := shared.PushFront(-i)
+		expect(pushed.tree.middle.build == nil).ToBe(false)
+		expectSequenceMatches(t, pushed, concatSlices([]interface{}{-i}, model))
+		expectFingerTreeInvariants(t, pushed)
+	}
+	expectSequenceMatches(t, shared, model)
+}
+
+// Versions shared between goroutines build their middle trees once
+func TestDequeSharedBetweenGoroutines(t *testing.T) {
+	deque := NewDeque()
+	for i := 0; i < 1000; i++ {
+		deque = deque.PushFront(i).PushBack(i)
+	}
+	model := deque.ToSlice()
+	pushed := deque.PushFront(-1)
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			popped, _, _ := pushed.PopFront()
+			for iterator, i := popped.Iterator(), 0; iterator.MoveNext(); i++ {
+				if iterator.Current() != model[i] {
+					t.Errorf("expected %v at %d, got %v", model[i], i, iterator.Current())
+					return
+				}
+			}
+		}()
+	}
+	wait.Wait()
+}
+
+// Applies random operations to a deque and to a slice, and checks
+// that they always agree
+func TestDequeMatchesSliceModel(t *testing.T) {
+	random := fakerFor(t)
+	type state struct {
+		deque *Deque
+		model []interface{}
+	}
+	states := []state{{NewDeque(), []interface{}{}}}
+	pick := func() state {
+		return states[random.rand.Intn(len(states))]
+	}
+	next := 0
+	for step := 0; step < 2000; step++ {
+		current := pick()
+		deque, model := current.deque, current.model
+		switch random.rand.Intn(6) {
+		case 0:
+			for i := random.rand.Intn(40); i > 0; i-- {
+				if random.rand.Intn(2) == 0 {
+					deque = deque.PushFront(next)
+					model = concatSlices([]interface{}{next}, model)
+				} else {
+					deque = deque.PushBack(next)
+					model = concatSlices(model, []interface{}{next})
+				}
+				next++
+			}
+		case 1:
+			for i := random.rand.Intn(20); i > 0 && len(model) > 0; i-- {
+				var value interface{}
+				if random.rand.Intn(2) == 0 {
+					deque, value, _ = deque.PopFront()
+					if value != model[0] {
+						t.Fatalf("popped %v from the front, expected %v", value, model[0])
+					}
+					model = model[1:]
+				} else {
+					deque, value, _ = deque.PopBack()
+					if value != model[len(model)-1] {
+						t.Fatalf("popped %v from the back, expected %v", value, model[len(model)-1])
+					}
+					model = model[:len(model)-1]
+				}
+			}
+		case 2:
+			if len(model) > 0 {
+				index := random.rand.Intn(len(model))
+				deque = deque.Update(index, next).(*Deque)
+				model = concatSlices(model)
+				model[index] = next
+				next++
+			}
+		case 3:
+			other := pick()
+			deque = deque.Concat(other.deque)
+			model = concatSlices(model, other.model)
+		case 4:
+			index := random.rand.Intn(len(model) + 1)
+			before, after := deque.SplitAt(index)
+			if random.rand.Intn(2) == 0 {
+				deque, model = before, model[:index]
+			} else {
+				deque, model = after, model[index:]
+			}
+		case 5:
+			start := random.rand.Intn(len(model) + 1)
+			end := start + random.rand.Intn(len(model)-start+1)
+			deque = deque.Slice(start, end).(*Deque)
+			model = model[start:end]
+		}
+		expectFingerTreeInvariants(t, deque)
+		expectSequenceMatches(t, deque, model)
+		if len(model) < 20000 {
+			states = append(states, state{deque, model})
+		}
+	}
+	// Earlier versions are unaffected by everything derived from them
+	for _, state := range states {
+		expectSequenceMatches(t, state.deque, state.model)
+	}
+}
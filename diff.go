@@ -0,0 +1,253 @@
+package collections
+
+// A HashMapDiff describes how one version of a HashMap differs from
+// another. See Diff.
+type HashMapDiff struct {
+	oldMap *HashMap
+	newMap *HashMap
+}
+
+// A ChangedEntry is a key whose value differs between the two maps
+// of a HashMapDiff
+type ChangedEntry struct {
+	Key      interface{}
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// Returns the differences between two versions of a HashMap.
+//
+// Nothing is compared until one of the iterables of the diff is
+// iterated. When both maps have the same hashing, which they do if one
+// was derived from the other, the comparison walks both tries together
+// and skips every sub-tree they share, so its cost depends on how much
+// changed between the versions rather than on their size.
+func Diff(oldMap *HashMap, newMap *HashMap) *HashMapDiff {
+	return &HashMapDiff{
+		oldMap: oldMap,
+		newMap: newMap,
+	}
+}
+
+// Returns a lazy Iterable over the entries of the new map whose keys
+// are not in the old map. Yields MapEntry values.
+func (diff *HashMapDiff) Added() Iterable {
+	if !diff.structural() {
+		return diff.newMap.Filter(func(entry interface{}) bool {
+			return !diff.oldMap.Contains(entry.(MapEntry).Key)
+		})
+	}
+	return diff.events(func(event trieDiffEvent) (interface{}, bool) {
+		if event.oldEntry != nil {
+			return nil, false
+		}
+		return MapEntry{Key: event.newEntry.key, Value: event.newEntry.value}, true
+	})
+}
+
+// Returns a lazy Iterable over the entries of the old map whose keys
+// are not in the new map. Yields MapEntry values.
+func (diff *HashMapDiff) Removed() Iterable {
+	if !diff.structural() {
+		return diff.oldMap.Filter(func(entry interface{}) bool {
+			return !diff.newMap.Contains(entry.(MapEntry).Key)
+		})
+	}
+	return diff.events(func(event trieDiffEvent) (interface{}, bool) {
+		if event.newEntry != nil {
+			return nil, false
+		}
+		return MapEntry{Key: event.oldEntry.key, Value: event.oldEntry.value}, true
+	})
+}
+
+// Returns a lazy Iterable over the keys in both maps whose values
+// differ. Yields ChangedEntry values.
+func (diff *HashMapDiff) Changed() Iterable {
+	if !diff.structural() {
+		return diff.newMap.Filter(func(item interface{}) bool {
+			entry := item.(MapEntry)
+			oldValue, found := diff.oldMap.Get(entry.Key)
+			return found && !sameValue(oldValue, entry.Value)
+		}).Map(func(item interface{}) interface{} {
+			entry := item.(MapEntry)
+			oldValue, _ := diff.oldMap.Get(entry.Key)
+			return ChangedEntry{Key: entry.Key, OldValue: oldValue, NewValue: entry.Value}
+		})
+	}
+	return diff.events(func(event trieDiffEvent) (interface{}, bool) {
+		if event.oldEntry == nil || event.newEntry == nil {
+			return nil, false
+		}
+		return ChangedEntry{Key: event.newEntry.key, OldValue: event.oldEntry.value, NewValue: event.newEntry.value}, true
+	})
+}
+
+func (diff *HashMapDiff) structural() bool {
+	return sameHashing(diff.oldMap.hasher, diff.newMap.hasher)
+}
+
+// Returns a Stream over the results of selectFn for the
+// differences it selects
+func (diff *HashMapDiff) events(selectFn func(event trieDiffEvent) (interface{}, bool)) Iterable {
+	return NewStream(&trieDiffIterator{
+		pending:  []trieDiffPair{{oldNode: diff.oldMap.root, newNode: diff.newMap.root}},
+		selectFn: selectFn,
+	})
+}
+
+// One difference between two tries: an entry only in the old trie,
+// only in the new trie, or in both with different values
+type trieDiffEvent struct {
+	oldEntry *KeyValueNode
+	newEntry *KeyValueNode
+}
+
+// A pair of sub-tries at the same position in the two tries
+type trieDiffPair struct {
+	oldNode HAMTNode
+	newNode HAMTNode
+	depth   hashKeyType
+}
+
+// Walks two tries built with the same hashing side by side, one pair
+// of nodes at a time, buffering the differences found in each
+type trieDiffIterator struct {
+	pending  []trieDiffPair
+	events   []trieDiffEvent
+	selectFn func(event trieDiffEvent) (interface{}, bool)
+	current  interface{}
+	started  bool
+	done     bool
+}
+
+func (iterator *trieDiffIterator) MoveNext() bool {
+	iterator.started = true
+	for {
+		for len(iterator.events) > 0 {
+			event := iterator.events[0]
+			iterator.events = iterator.events[1:]
+			if result, ok := iterator.selectFn(event); ok {
+				iterator.current = result
+				return true
+			}
+		}
+		if len(iterator.pending) == 0 {
+			iterator.done = true
+			iterator.current = nil
+			return false
+		}
+		pair := iterator.pending[len(iterator.pending)-1]
+		iterator.pending = iterator.pending[:len(iterator.pending)-1]
+		iterator.compare(pair)
+	}
+}
+
+func (iterator *trieDiffIterator) Current() interface{} {
+	if !iterator.started || iterator.done {
+		panic(ErrIterationOutOfRange)
+	}
+	return iterator.current
+}
+
+func (iterator *trieDiffIterator) emit(oldEntry *KeyValueNode, newEntry *KeyValueNode) {
+	iterator.events = append(iterator.events, trieDiffEvent{oldEntry: oldEntry, newEntry: newEntry})
+}
+
+// Buffers the differences between a pair of nodes, queueing pairs
+// of sub-nodes that need to be compared in turn
+func (iterator *trieDiffIterator) compare(pair trieDiffPair) {
+	if pair.oldNode == pair.newNode {
+		return
+	}
+	oldSlice, oldOk := pair.oldNode.(*SliceNode)
+	newSlice, newOk := pair.newNode.(*SliceNode)
+	if !oldOk || !newOk {
+		iterator.compareByLookup(pair.oldNode, pair.newNode, pair.depth)
+		return
+	}
+
+	occupied := oldSlice.dataMap | oldSlice.nodeMap | newSlice.dataMap | newSlice.nodeMap
+	for ; occupied != 0; occupied &^= lowestBit(occupied) {
+		bit := lowestBit(occupied)
+		oldSlot, newSlot := oldSlice.slot(bit), newSlice.slot(bit)
+		switch {
+		case oldSlot.entry != nil && newSlot.entry != nil:
+			iterator.compareEntries(oldSlot.entry, newSlot.entry)
+		case oldSlot.entry != nil && newSlot.isEmpty():
+			iterator.emit(oldSlot.entry, nil)
+		case oldSlot.isEmpty() && newSlot.entry != nil:
+			iterator.emit(nil, newSlot.entry)
+		case newSlot.isEmpty():
+			forEachEntry(oldSlot.node, func(entry *KeyValueNode) {
+				iterator.emit(entry, nil)
+			})
+		case oldSlot.isEmpty():
+			forEachEntry(newSlot.node, func(entry *KeyValueNode) {
+				iterator.emit(nil, entry)
+			})
+		case oldSlot.entry != nil:
+			iterator.compareEntryWithNode(oldSlot.entry, newSlot.node, true)
+		case newSlot.entry != nil:
+			iterator.compareEntryWithNode(newSlot.entry, oldSlot.node, false)
+		default:
+			iterator.pending = append(iterator.pending, trieDiffPair{
+				oldNode: oldSlot.node,
+				newNode: newSlot.node,
+				depth:   pair.depth + 1,
+			})
+		}
+	}
+}
+
+func (iterator *trieDiffIterator) compareEntries(oldEntry *KeyValueNode, newEntry *KeyValueNode) {
+	if !sameKey(oldEntry, newEntry) {
+		iterator.emit(oldEntry, nil)
+		iterator.emit(nil, newEntry)
+	} else if !sameValue(oldEntry.value, newEntry.value) {
+		iterator.emit(oldEntry, newEntry)
+	}
+}
+
+// Compares a lone entry with the sub-node in the same slot of the
+// other trie. isOld tells which trie the entry is from.
+func (iterator *trieDiffIterator) compareEntryWithNode(entry *KeyValueNode, node HAMTNode, isOld bool) {
+	found := false
+	forEachEntry(node, func(other *KeyValueNode) {
+		if sameKey(entry, other) {
+			found = true
+			if isOld {
+				iterator.compareEntries(entry, other)
+			} else {
+				iterator.compareEntries(other, entry)
+			}
+		} else if isOld {
+			iterator.emit(nil, other)
+		} else {
+			iterator.emit(other, nil)
+		}
+	})
+	if !found && isOld {
+		iterator.emit(entry, nil)
+	} else if !found {
+		iterator.emit(nil, entry)
+	}
+}
+
+// The fallback for compare where the two nodes don't line up slot
+// by slot because one of them is a CollisionNode
+func (iterator *trieDiffIterator) compareByLookup(oldNode HAMTNode, newNode HAMTNode, depth hashKeyType) {
+	forEachEntry(oldNode, func(oldEntry *KeyValueNode) {
+		newEntry := findEntry(newNode, oldEntry.originalHash, depth, oldEntry.key)
+		if newEntry == nil {
+			iterator.emit(oldEntry, nil)
+		} else if !sameValue(oldEntry.value, newEntry.value) {
+			iterator.emit(oldEntry, newEntry)
+		}
+	})
+	forEachEntry(newNode, func(newEntry *KeyValueNode) {
+		if findEntry(oldNode, newEntry.originalHash, depth, newEntry.key) == nil {
+			iterator.emit(nil, newEntry)
+		}
+	})
+}
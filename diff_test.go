@@ -0,0 +1,148 @@
+package collections
+
+import (
+	"testing"
+)
+
+type diffModel struct {
+	added   map[int]int
+	removed map[int]int
+	changed map[int][2]int
+}
+
+func expectDiffMatches(t *testing.T, diff *HashMapDiff, model diffModel) {
+	t.Helper()
+	expect := expectFor(t)
+	added := map[int]int{}
+	diff.Added().ForEach(func(item interface{}) {
+		entry := item.(MapEntry)
+		added[entry.Key.(int)] = entry.Value.(int)
+	})
+	removed := map[int]int{}
+	diff.Removed().ForEach(func(item interface{}) {
+		entry := item.(MapEntry)
+		removed[entry.Key.(int)] = entry.Value.(int)
+	})
+	changed := map[int][2]int{}
+	diff.Changed().ForEach(func(item interface{}) {
+		entry := item.(ChangedEntry)
+		changed[entry.Key.(int)] = [2]int{entry.OldValue.(int), entry.NewValue.(int)}
+	})
+	expect(added).ToDeepEqual(model.added)
+	expect(removed).ToDeepEqual(model.removed)
+	expect(changed).ToDeepEqual(model.changed)
+}
+
+func TestDiffMatchesModel(t *testing.T) {
+	random := fakerFor(t)
+	shared := &keyHasher{seed: randomHashSeed()}
+	hashers := map[string][2]*keyHasher{
+		"shared":     {shared, shared},
+		"collisions": {{seed: randomHashSeed(), hashFn: moduloSixteenHash}, nil},
+		"mismatched": {shared, {seed: randomHashSeed(), hashFn: moduloSixteenHash}},
+	}
+	for name, pair := range hashers {
+		if pair[1] == nil {
+			pair[1] = pair[0]
+		}
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 30; i++ {
+				oldMap := newHashMapWithHasher(pair[0])
+				oldModel := map[int]int{}
+				for j := random.rand.Intn(400); j > 0; j-- {
+					key := random.rand.Intn(500)
+					oldMap = oldMap.set(key, j)
+					oldModel[key] = j
+				}
+				newMap := newHashMapWithHasher(pair[1]).Merge(oldMap).(*HashMap)
+				newModel := map[int]int{}
+				for key, value := range oldModel {
+					newModel[key] = value
+				}
+				for j := random.rand.Intn(100); j > 0; j-- {
+					key := random.rand.Intn(500)
+					if random.rand.Intn(2) == 0 {
+						newMap = newMap.remove(key)
+						delete(newModel, key)
+					} else {
+						newModel[key] = random.rand.Intn(3)
+						newMap = newMap.set(key, newModel[key])
+					}
+				}
+
+				model := diffModel{added: map[int]int{}, removed: map[int]int{}, changed: map[int][2]int{}}
+				for key, value := range newModel {
+					if oldValue, found := oldModel[key]; !found {
+						model.added[key] = value
+					} else if oldValue != value {
+						model.changed[key] = [2]int{oldValue, value}
+					}
+				}
+				for key, value := range oldModel {
+					if _, found := newModel[key]; !found {
+						model.removed[key] = value
+					}
+				}
+				expectDiffMatches(t, Diff(oldMap, newMap), model)
+			}
+		})
+	}
+}
+
+func TestDiffOfSameMapIsEmpty(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().Set(1, 1).Set(2, 2).(*HashMap)
+	diff := Diff(m, m)
+	expect(diff.Added().ToSlice()).ToDeepEqual([]interface{}{})
+	expect(diff.Removed().ToSlice()).ToDeepEqual([]interface{}{})
+	expect(diff.Changed().ToSlice()).ToDeepEqual([]interface{}{})
+	expect(Diff(NewHashMap(), NewHashMap()).Added().ToSlice()).ToDeepEqual([]interface{}{})
+}
+
+// A key that counts how often it is compared
+type countedKey struct {
+	id    int
+	count *int
+}
+
+func (key countedKey) Hash() uint64 {
+	return uint64(key.id)
+}
+
+func (key countedKey) Equals(other interface{}) bool {
+	*key.count++
+	otherKey, ok := other.(countedKey)
+	return ok && otherKey.id == key.id
+}
+
+func TestDiffSkipsSharedSubtrees(t *testing.T) {
+	expect := expectFor(t)
+	comparisons := 0
+	oldMap := NewHashMap().Transient()
+	for i := 0; i < 100000; i++ {
+		oldMap.Set(countedKey{id: i, count: &comparisons}, i)
+	}
+	before := oldMap.Persistent()
+	after := before.set(countedKey{id: 5, count: &comparisons}, -5).set(countedKey{id: 100001, count: &comparisons}, 1)
+
+	comparisons = 0
+	diff := Diff(before, after)
+	expect(len(diff.Changed().ToSlice())).ToBe(1)
+	expect(len(diff.Added().ToSlice())).ToBe(1)
+	expect(len(diff.Removed().ToSlice())).ToBe(0)
+	expect(comparisons < 100).ToBe(true)
+
+	changed := diff.Changed().ToSlice()[0].(ChangedEntry)
+	expect(changed.OldValue).ToBe(5)
+	expect(changed.NewValue).ToBe(-5)
+}
+
+func TestDiffIteratorBounds(t *testing.T) {
+	expect := expectFor(t)
+	iterator := Diff(NewHashMap(), NewHashMap().set(1, 1)).Added().Iterator()
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+	expect(iterator.MoveNext()).ToBe(true)
+	expect(iterator.Current()).ToBe(MapEntry{Key: 1, Value: 1})
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+}
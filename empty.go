@@ -37,6 +37,10 @@ func (iterable *EmptyIterable) ToSlice() []interface{} {
 	return []interface{}{}
 }
 
+func (iterable *EmptyIterable) ToVector() *Vector {
+	return NewVector()
+}
+
 func (iterable *EmptyIterable) Take(count int) Iterable {
 	return iterable
 }
@@ -49,6 +53,14 @@ func (iterable *EmptyIterable) SkipWhile(matchFn func(interface{}) bool) Iterabl
 	return iterable
 }
 
+func (iterable *EmptyIterable) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return NewHashMap()
+}
+
+func (iterable *EmptyIterable) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return NewHashMap()
+}
+
 func (iterable *EmptyIterable) Any(matchFn func(interface{}) bool) bool {
 	return false
 }
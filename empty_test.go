@@ -85,3 +85,14 @@ func TestEmptyIterableSkipWhile(t *testing.T) {
 	}).ToSlice()
 	expect(result).ToDeepEqual([]interface{}{})
 }
+
+func TestEmptyIterableGroupBy(t *testing.T) {
+	expect := expectFor(t)
+	groups := NewEmptyIterable().GroupBy(func(v interface{}) interface{} {
+		t.Fatalf("An empty iterable should never group")
+		return v
+	})
+	expect(groups.(*HashMap).Size()).ToBe(0)
+	aggregates := NewEmptyIterable().GroupByAggregate(func(v interface{}) interface{} { return v }, 0, nil)
+	expect(aggregates.(*HashMap).Size()).ToBe(0)
+}
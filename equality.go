@@ -0,0 +1,132 @@
+package collections
+
+import (
+	"reflect"
+)
+
+// Value equality and hashing for collections, so that collections
+// implement Hashable and can be nested as keys of hash based collections.
+//
+// Equality is by category rather than by type:
+//   - Sequences are equal when they have equal elements in the same
+//     order, so a Stack can equal a SliceSequence.
+//   - Sets are equal when they have the same elements, whatever
+//     their iteration order.
+//   - Maps are equal when they have the same keys, with equal values.
+//
+// Elements, keys and values are compared as keys are (see hash.go),
+// except that values that can't be compared with ==, such as slices,
+// are compared with reflect.DeepEqual. Hashing a collection hashes its
+// contents with a fixed seed, so unless they include pointers the hash
+// is the same in every process. It is O(n) on every call. Values that
+// can't be hashed all hash the same, so they don't make Hash panic.
+
+// Tags mixed into collection hashes, so that e.g. an empty sequence
+// and an empty set don't share a hash
+const (
+	sequenceHashTag uint64 = iota + 1
+	setHashTag
+	mapHashTag
+	unhashableHashTag
+)
+
+// The hashing of collection contents. Its seed is fixed, so that a
+// collection hashes the same in every process, and a map with a fixed
+// seed keyed by collections iterates in the same order on every run.
+var contentHasher = &keyHasher{
+	seed: fixedHashSeed(0),
+}
+
+// Whether two elements of collections are equal
+func valuesEqual(a interface{}, b interface{}) bool {
+	if hashable, ok := a.(Hashable); ok {
+		return hashable.Equals(b)
+	}
+	if _, ok := b.(Hashable); ok {
+		return false
+	}
+	if a != nil && b != nil && (!comparableValue(reflect.ValueOf(a)) || !comparableValue(reflect.ValueOf(b))) {
+		return reflect.DeepEqual(a, b)
+	}
+	return sameValue(a, b)
+}
+
+func hashOfValue(value interface{}) uint64 {
+	if _, ok := value.(Hashable); !ok && value != nil && !comparableValue(reflect.ValueOf(value)) {
+		return unhashableHashTag
+	}
+	return contentHasher.hash(value)
+}
+
+func equalSequences(sequence Sequence, other interface{}) bool {
+	if sequence == other {
+		return true
+	}
+	otherSequence, ok := other.(Sequence)
+	if !ok || sequence.Size() != otherSequence.Size() {
+		return false
+	}
+	iterator, otherIterator := sequence.Iterator(), otherSequence.Iterator()
+	for iterator.MoveNext() && otherIterator.MoveNext() {
+		if !valuesEqual(iterator.Current(), otherIterator.Current()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Combines the element hashes in order
+func hashOfSequence(sequence Sequence) uint64 {
+	hash := sequenceHashTag
+	sequence.ForEach(func(item interface{}) {
+		hash = mixBits(hash ^ hashOfValue(item))
+	})
+	return mixBits(hash ^ uint64(sequence.Size()))
+}
+
+func equalSets(set Set, other interface{}) bool {
+	if set == other {
+		return true
+	}
+	otherSet, ok := other.(Set)
+	if !ok || set.Size() != otherSet.Size() {
+		return false
+	}
+	return !set.Any(func(item interface{}) bool {
+		return !otherSet.Contains(item)
+	})
+}
+
+// Sums the element hashes, so the order doesn't matter
+func hashOfSet(set Set) uint64 {
+	var sum uint64
+	set.ForEach(func(item interface{}) {
+		sum += mixBits(hashOfValue(item))
+	})
+	return mixBits(sum ^ setHashTag)
+}
+
+func equalMaps(m Map, other interface{}) bool {
+	if m == other {
+		return true
+	}
+	otherMap, ok := other.(Map)
+	if !ok || m.Size() != otherMap.Size() {
+		return false
+	}
+	return !m.Any(func(item interface{}) bool {
+		entry := item.(MapEntry)
+		value, found := otherMap.Get(entry.Key)
+		return !found || !valuesEqual(entry.Value, value)
+	})
+}
+
+// Sums the entry hashes, so the order doesn't matter
+func hashOfMap(m Map) uint64 {
+	var sum uint64
+	m.ForEach(func(item interface{}) {
+		entry := item.(MapEntry)
+		sum += mixBits(hashOfValue(entry.Key) ^ mixBits(hashOfValue(entry.Value)))
+	})
+	return mixBits(sum ^ mapHashTag)
+}
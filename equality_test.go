@@ -0,0 +1,112 @@
+package collections
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSequenceEquality(t *testing.T) {
+	expect := expectFor(t)
+	stack := NewStack().Push(3).Push(2).Push(1)
+	slice := NewSliceSequence(1, 2, 3)
+	expect(stack.Equals(slice)).ToBe(true)
+	expect(slice.Equals(stack)).ToBe(true)
+	expect(stack.Hash()).ToBe(slice.Hash())
+	expect(NewStack().Push(1).Equals(NewSliceSequence(1))).ToBe(true)
+
+	expect(slice.Equals(NewSliceSequence(3, 2, 1))).ToBe(false)
+	expect(slice.Equals(NewSliceSequence(1, 2))).ToBe(false)
+	expect(slice.Equals(NewHashSet(1, 2, 3))).ToBe(false)
+	expect(slice.Equals("123")).ToBe(false)
+	expect(NewStack().Equals(NewSliceSequence())).ToBe(true)
+	expect(NewStack().Hash()).ToBe(NewSliceSequence().Hash())
+	expect(slice.Hash()).Not().ToBe(NewSliceSequence(3, 2, 1).Hash())
+}
+
+func TestSetEquality(t *testing.T) {
+	expect := expectFor(t)
+	hashSet := NewHashSet(1, 2, 3)
+	sortedSet := NewSortedSet(compareInts, 3, 2, 1)
+	expect(hashSet.Equals(sortedSet)).ToBe(true)
+	expect(sortedSet.Equals(hashSet)).ToBe(true)
+	expect(hashSet.Hash()).ToBe(sortedSet.Hash())
+	expect(hashSet.Equals(NewHashSetWithSeed(5, 3, 1, 2))).ToBe(true)
+	expect(hashSet.Equals(NewHashSet(1, 2, 3))).ToBe(true)
+
+	expect(hashSet.Equals(NewHashSet(1, 2))).ToBe(false)
+	expect(hashSet.Equals(NewHashSet(1, 2, 4))).ToBe(false)
+	expect(hashSet.Equals(NewSliceSequence(1, 2, 3))).ToBe(false)
+	expect(hashSet.Hash()).Not().ToBe(NewHashSet(1, 2, 4).Hash())
+}
+
+func TestMapEquality(t *testing.T) {
+	expect := expectFor(t)
+	hashMap := NewHashMap().Set("a", 1).Set("b", 2)
+	others := []Map{
+		NewHashMapWithSeed(7).Set("b", 2).Set("a", 1),
+		NewLinkedHashMap().Set("b", 2).Set("a", 1),
+		NewSortedMap(compareStrings).Set("a", 1).Set("b", 2),
+		NewBiMap().Set("a", 1).Set("b", 2),
+	}
+	for _, other := range others {
+		expect(hashMap.Equals(other)).ToBe(true)
+		expect(other.Equals(hashMap)).ToBe(true)
+		expect(other.Hash()).ToBe(hashMap.Hash())
+	}
+
+	expect(hashMap.Equals(hashMap.Set("a", 10))).ToBe(false)
+	expect(hashMap.Equals(hashMap.Set("c", 3))).ToBe(false)
+	expect(hashMap.Equals(NewHashMap().Set("a", 1).Set("c", 2))).ToBe(false)
+	expect(hashMap.Equals(hashMap.KeySet())).ToBe(false)
+	expect(hashMap.Hash()).Not().ToBe(hashMap.Set("a", 10).Hash())
+}
+
+func TestCollectionsAsKeys(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().
+		Set(NewSliceSequence(1, 2), "sequence").
+		Set(NewHashSet("x", "y"), "set").
+		Set(NewHashMap().Set("k", "v"), "map")
+
+	val, found := m.Get(NewStack().Push(2).Push(1))
+	expect(val).ToBe("sequence")
+	expect(found).ToBe(true)
+	val, _ = m.Get(NewSortedSet(compareStrings, "y", "x"))
+	expect(val).ToBe("set")
+	val, _ = m.Get(NewLinkedHashMap().Set("k", "v"))
+	expect(val).ToBe("map")
+	_, found = m.Get(NewSliceSequence(2, 1))
+	expect(found).ToBe(false)
+
+	// Nested collections compare by value all the way down
+	nested := NewHashSet(NewSliceSequence(NewHashSet(1, 2)))
+	expect(nested.Contains(NewStack().Push(NewHashSet(2, 1)))).ToBe(true)
+}
+
+func TestUncomparableValues(t *testing.T) {
+	expect := expectFor(t)
+	first := NewSliceSequence([]int{1})
+	expect(first.Equals(NewSliceSequence([]int{1}))).ToBe(true)
+	expect(first.Equals(NewSliceSequence([]int{2}))).ToBe(false)
+	expect(first.Hash()).ToBe(NewSliceSequence([]int{1}).Hash())
+	m := NewHashMap().Set(1, []int{1})
+	expect(m.Equals(m)).ToBe(true)
+	expect(m.Equals(NewHashMap().Set(1, []int{1}))).ToBe(true)
+	expect(m.Hash()).ToBe(NewHashMap().Set(1, []int{1}).Hash())
+	set := NewHashSet(1)
+	expect(set.Equals(set)).ToBe(true)
+}
+
+// Hashes of collections must not change between processes, or maps
+// with a fixed seed keyed by collections would iterate differently
+// on every run
+func TestCollectionHashesAreStable(t *testing.T) {
+	expect := expectFor(t)
+	expect(NewVector(1, "two", 3.0).Hash()).ToBe(uint64(5333887145111021736))
+	expect(NewHashSet("a", "b").Hash()).ToBe(uint64(11564798053467945026))
+	expect(NewHashMap().Set("a", 1).Hash()).ToBe(uint64(12662946945518304559))
+}
+
+func compareStrings(a interface{}, b interface{}) int {
+	return strings.Compare(a.(string), b.(string))
+}
@@ -23,6 +23,18 @@ var ErrInvalidSkipArgument = errors.New("count in Skip must be non-negative")
 // Error for when a negative integer is passed to take
 var ErrInvalidTakeArgument = errors.New("count in Skip must be non-negative")
 
+// Error for when a value used as a key of a hash based collection
+// can't be hashed, e.g. because it is or contains a slice, map or function
 var ErrUnhashableType = errors.New("unhashable type")
 
+// Error for when a transient collection is used after it has
+// been frozen with Persistent
+var ErrTransientFrozen = errors.New("transient used after Persistent")
+
+// Error for when a BiMap Set would give a value a second key
+var ErrDuplicateValue = errors.New("value already belongs to another key")
+
+// Error wrapped by HashMap.Validate when the trie of a map is broken
+var ErrInvalidTrie = errors.New("invalid hash trie")
+
 var ErrImpossible = errors.New("impossible state reached. Something is wrong in collections source code")
@@ -0,0 +1,477 @@
+package collections
+
+import (
+	"sync"
+)
+
+// A persistent 2-3 finger tree, the structure behind Deque.
+//
+// The layout follows Hinze and Paterson ("Finger trees: a simple
+// general-purpose data structure", JFP 2006), measured by size. A tree
+// is empty, a single item, or deep: a front and a back digit of one to
+// four items each, with a middle tree between them whose items are 2-3
+// nodes of the items one level up. Items at the top level are the
+// elements of the deque, and each level below holds nodes of the level
+// above, so the ends of the tree are always within reach.
+//
+// As in the paper, the middle tree is suspended: when a push overflows
+// a digit, or a pop empties one, the matching change to the middle tree
+// is only made the first time the middle tree is needed, and then
+// remembered. Without that, repeatedly pushing onto an old version
+// whose digits are full at every level would cost O(log n) every time.
+// With it, pushing and popping at either end are amortized O(1) even
+// when versions are shared, while concatenation and splitting are
+// O(log n). The middle tree a change is suspended over is forced first,
+// so that suspended changes never pile up on one another, and forcing
+// one never recurses deeper than the tree.
+//
+// No tree, node or digit is ever modified once built, so every version
+// of a tree shares most of its structure with the version it was
+// derived from. A nil *fingerTree is an empty tree.
+type fingerTree struct {
+	size int
+	// The item of a single tree, which has no front or back
+	single interface{}
+	front  []interface{}
+	middle *lazyFingerTree
+	back   []interface{}
+}
+
+// A tree that is built the first time it is forced, and is then
+// remembered, so that every version sharing it builds it once. The size
+// is known up front. A nil *lazyFingerTree is an empty tree, and any
+// other has a size of at least one.
+type lazyFingerTree struct {
+	size  int
+	once  sync.Once
+	build func() *fingerTree
+	tree  *fingerTree
+}
+
+// Returns a lazy tree that is built by calling build
+func suspendFingerTree(size int, build func() *fingerTree) *lazyFingerTree {
+	if size == 0 {
+		return nil
+	}
+	return &lazyFingerTree{
+		size:  size,
+		build: build,
+	}
+}
+
+// Returns a lazy tree that is already built
+func readyFingerTree(tree *fingerTree) *lazyFingerTree {
+	if tree == nil {
+		return nil
+	}
+	return &lazyFingerTree{
+		size: tree.size,
+		tree: tree,
+	}
+}
+
+// Builds the tree if it hasn't been built yet, and returns it
+func (lazy *lazyFingerTree) force() *fingerTree {
+	if lazy == nil {
+		return nil
+	}
+	lazy.once.Do(func() {
+		if lazy.build != nil {
+			lazy.tree = lazy.build()
+			lazy.build = nil
+		}
+	})
+	return lazy.tree
+}
+
+func (lazy *lazyFingerTree) treeSize() int {
+	if lazy == nil {
+		return 0
+	}
+	return lazy.size
+}
+
+// A branch of 2 or 3 items of the level above, which caches the
+// number of elements below it
+type fingerNode struct {
+	size  int
+	items []interface{}
+}
+
+// The number of elements in an item. Only the tree itself builds
+// fingerNodes, so an item that isn't one is an element.
+func fingerItemSize(item interface{}) int {
+	if node, ok := item.(*fingerNode); ok {
+		return node.size
+	}
+	return 1
+}
+
+func fingerItemsSize(items []interface{}) int {
+	size := 0
+	for _, item := range items {
+		size += fingerItemSize(item)
+	}
+	return size
+}
+
+func fingerTreeSize(tree *fingerTree) int {
+	if tree == nil {
+		return 0
+	}
+	return tree.size
+}
+
+func newFingerNode(items ...interface{}) *fingerNode {
+	return &fingerNode{
+		size:  fingerItemsSize(items),
+		items: items,
+	}
+}
+
+func newSingleFingerTree(item interface{}) *fingerTree {
+	return &fingerTree{
+		size:   fingerItemSize(item),
+		single: item,
+	}
+}
+
+func newDeepFingerTree(front []interface{}, middle *lazyFingerTree, back []interface{}) *fingerTree {
+	return &fingerTree{
+		size:   fingerItemsSize(front) + middle.treeSize() + fingerItemsSize(back),
+		front:  front,
+		middle: middle,
+		back:   back,
+	}
+}
+
+// Whether the tree holds a single item rather than two digits
+func (tree *fingerTree) isSingle() bool {
+	return len(tree.front) == 0
+}
+
+// Returns a tree holding the items, of which there are at most four
+func fingerTreeFromItems(items []interface{}) *fingerTree {
+	switch len(items) {
+	case 0:
+		return nil
+	case 1:
+		return newSingleFingerTree(items[0])
+	}
+	half := len(items) / 2
+	return newDeepFingerTree(items[:half:half], nil, items[half:])
+}
+
+// Returns a copy of the tree with item added at the front
+func pushFingerFront(tree *fingerTree, item interface{}) *fingerTree {
+	if tree == nil {
+		return newSingleFingerTree(item)
+	}
+	if tree.isSingle() {
+		return newDeepFingerTree([]interface{}{item}, nil, []interface{}{tree.single})
+	}
+	if len(tree.front) < 4 {
+		front := make([]interface{}, len(tree.front)+1)
+		front[0] = item
+		copy(front[1:], tree.front)
+		return newDeepFingerTree(front, tree.middle, tree.back)
+	}
+	// The front digit is full, so three of its items move
+	// down into the middle tree as a node, once it is needed
+	node := newFingerNode(tree.front[1], tree.front[2], tree.front[3])
+	middle := tree.middle
+	middle.force()
+	return newDeepFingerTree([]interface{}{item, tree.front[0]}, suspendFingerTree(middle.treeSize()+node.size, func() *fingerTree {
+		return pushFingerFront(middle.force(), node)
+	}), tree.back)
+}
+
+// Returns a copy of the tree with item added at the back
+func pushFingerBack(tree *fingerTree, item interface{}) *fingerTree {
+	if tree == nil {
+		return newSingleFingerTree(item)
+	}
+	if tree.isSingle() {
+		return newDeepFingerTree([]interface{}{tree.single}, nil, []interface{}{item})
+	}
+	if len(tree.back) < 4 {
+		back := make([]interface{}, len(tree.back)+1)
+		copy(back, tree.back)
+		back[len(tree.back)] = item
+		return newDeepFingerTree(tree.front, tree.middle, back)
+	}
+	node := newFingerNode(tree.back[0], tree.back[1], tree.back[2])
+	middle := tree.middle
+	middle.force()
+	return newDeepFingerTree(tree.front, suspendFingerTree(middle.treeSize()+node.size, func() *fingerTree {
+		return pushFingerBack(middle.force(), node)
+	}), []interface{}{tree.back[3], item})
+}
+
+// Returns the first item of a non-empty tree, and the tree without it
+func popFingerFront(tree *fingerTree) (interface{}, *fingerTree) {
+	item, rest := viewFingerFront(tree)
+	return item, rest.force()
+}
+
+// Returns the last item of a non-empty tree, and the tree without it
+func popFingerBack(tree *fingerTree) (interface{}, *fingerTree) {
+	item, rest := viewFingerBack(tree)
+	return item, rest.force()
+}
+
+// Returns the first item of a non-empty tree, and the tree without it.
+// When that empties the front digit, refilling it from the middle tree
+// is put off until the rest is needed.
+func viewFingerFront(tree *fingerTree) (interface{}, *lazyFingerTree) {
+	if tree.isSingle() {
+		return tree.single, nil
+	}
+	first, front, middle, back := tree.front[0], tree.front[1:], tree.middle, tree.back
+	if len(front) > 0 {
+		return first, readyFingerTree(newDeepFingerTree(front, middle, back))
+	}
+	middle.force()
+	return first, suspendFingerTree(tree.size-fingerItemSize(first), func() *fingerTree {
+		return deepFingerTreeFront(front, middle, back)
+	})
+}
+
+// Returns the last item of a non-empty tree, and the tree without it,
+// putting off refilling the back digit as viewFingerFront does
+func viewFingerBack(tree *fingerTree) (interface{}, *lazyFingerTree) {
+	if tree.isSingle() {
+		return tree.single, nil
+	}
+	last := len(tree.back) - 1
+	item, front, middle, back := tree.back[last], tree.front, tree.middle, tree.back[:last:last]
+	if len(back) > 0 {
+		return item, readyFingerTree(newDeepFingerTree(front, middle, back))
+	}
+	middle.force()
+	return item, suspendFingerTree(tree.size-fingerItemSize(item), func() *fingerTree {
+		return deepFingerTreeBack(front, middle, back)
+	})
+}
+
+// Builds a tree from a front digit that may be empty, borrowing
+// a node from the middle tree to refill it
+func deepFingerTreeFront(front []interface{}, middle *lazyFingerTree, back []interface{}) *fingerTree {
+	if len(front) > 0 {
+		return newDeepFingerTree(front, middle, back)
+	}
+	if middle == nil {
+		return fingerTreeFromItems(back)
+	}
+	node, rest := viewFingerFront(middle.force())
+	return newDeepFingerTree(node.(*fingerNode).items, rest, back)
+}
+
+// Builds a tree from a back digit that may be empty, borrowing
+// a node from the middle tree to refill it
+func deepFingerTreeBack(front []interface{}, middle *lazyFingerTree, back []interface{}) *fingerTree {
+	if len(back) > 0 {
+		return newDeepFingerTree(front, middle, back)
+	}
+	if middle == nil {
+		return fingerTreeFromItems(front)
+	}
+	node, rest := viewFingerBack(middle.force())
+	return newDeepFingerTree(front, rest, node.(*fingerNode).items)
+}
+
+// Returns the first item of a non-empty tree
+func peekFingerFront(tree *fingerTree) interface{} {
+	if tree.isSingle() {
+		return tree.single
+	}
+	return tree.front[0]
+}
+
+// Returns the last item of a non-empty tree
+func peekFingerBack(tree *fingerTree) interface{} {
+	if tree.isSingle() {
+		return tree.single
+	}
+	return tree.back[len(tree.back)-1]
+}
+
+// Returns the items of left, then items, then the items of right
+func concatFingerTrees(left *fingerTree, items []interface{}, right *fingerTree) *fingerTree {
+	if left == nil {
+		for i := len(items) - 1; i >= 0; i-- {
+			right = pushFingerFront(right, items[i])
+		}
+		return right
+	}
+	if right == nil {
+		for _, item := range items {
+			left = pushFingerBack(left, item)
+		}
+		return left
+	}
+	if left.isSingle() {
+		return pushFingerFront(concatFingerTrees(nil, items, right), left.single)
+	}
+	if right.isSingle() {
+		return pushFingerBack(concatFingerTrees(left, items, nil), right.single)
+	}
+	seam := make([]interface{}, 0, len(left.back)+len(items)+len(right.front))
+	seam = append(seam, left.back...)
+	seam = append(seam, items...)
+	seam = append(seam, right.front...)
+	middle := concatFingerTrees(left.middle.force(), fingerNodesOf(seam), right.middle.force())
+	return newDeepFingerTree(left.front, readyFingerTree(middle), right.back)
+}
+
+// Groups between 2 and 12 items into nodes of 2 or 3 items
+func fingerNodesOf(items []interface{}) []interface{} {
+	nodes := []interface{}{}
+	for len(items) > 0 {
+		switch len(items) {
+		case 2, 4:
+			nodes = append(nodes, newFingerNode(items[0], items[1]))
+			items = items[2:]
+		default:
+			nodes = append(nodes, newFingerNode(items[0], items[1], items[2]))
+			items = items[3:]
+		}
+	}
+	return nodes
+}
+
+// Splits a non-empty tree around the item holding the element at
+// index, which must be in range. Returns the tree of items before
+// it, the item, the index of the element within the item, and the
+// tree of items after it.
+func splitFingerTree(tree *fingerTree, index int) (*fingerTree, interface{}, int, *fingerTree) {
+	if tree.isSingle() {
+		return nil, tree.single, index, nil
+	}
+	frontSize := fingerItemsSize(tree.front)
+	if index < frontSize {
+		before, item, index, after := splitFingerItems(tree.front, index)
+		return fingerTreeFromItems(before), item, index, deepFingerTreeFront(after, tree.middle, tree.back)
+	}
+	index -= frontSize
+	middleSize := tree.middle.treeSize()
+	if index < middleSize {
+		beforeMiddle, node, index, afterMiddle := splitFingerTree(tree.middle.force(), index)
+		before, item, index, after := splitFingerItems(node.(*fingerNode).items, index)
+		return deepFingerTreeBack(tree.front, readyFingerTree(beforeMiddle), before), item, index, deepFingerTreeFront(after, readyFingerTree(afterMiddle), tree.back)
+	}
+	index -= middleSize
+	before, item, index, after := splitFingerItems(tree.back, index)
+	return deepFingerTreeBack(tree.front, tree.middle, before), item, index, fingerTreeFromItems(after)
+}
+
+// Splits a digit or the items of a node around the item holding
+// the element at index, as splitFingerTree does
+func splitFingerItems(items []interface{}, index int) ([]interface{}, interface{}, int, []interface{}) {
+	for i, item := range items {
+		size := fingerItemSize(item)
+		if index < size {
+			return items[:i:i], item, index, items[i+1:]
+		}
+		index -= size
+	}
+	panic(ErrImpossible)
+}
+
+// Returns the element at index within the item
+func getFingerItem(item interface{}, index int) interface{} {
+	for {
+		node, ok := item.(*fingerNode)
+		if !ok {
+			return item
+		}
+		for _, child := range node.items {
+			size := fingerItemSize(child)
+			if index < size {
+				item = child
+				break
+			}
+			index -= size
+		}
+	}
+}
+
+// Returns the element at index, which must be in range
+func getFingerTree(tree *fingerTree, index int) interface{} {
+	if tree.isSingle() {
+		return getFingerItem(tree.single, index)
+	}
+	frontSize := fingerItemsSize(tree.front)
+	if index < frontSize {
+		return getFingerItems(tree.front, index)
+	}
+	index -= frontSize
+	if index < tree.middle.treeSize() {
+		return getFingerTree(tree.middle.force(), index)
+	}
+	return getFingerItems(tree.back, index-tree.middle.treeSize())
+}
+
+func getFingerItems(items []interface{}, index int) interface{} {
+	for _, item := range items {
+		size := fingerItemSize(item)
+		if index < size {
+			return getFingerItem(item, index)
+		}
+		index -= size
+	}
+	panic(ErrImpossible)
+}
+
+// Returns a copy of the item with the element at index replaced by value
+func updateFingerItem(item interface{}, index int, value interface{}) interface{} {
+	node, ok := item.(*fingerNode)
+	if !ok {
+		return value
+	}
+	return &fingerNode{
+		size:  node.size,
+		items: updateFingerItems(node.items, index, value),
+	}
+}
+
+// Returns a copy of the items with the element at index replaced by value
+func updateFingerItems(items []interface{}, index int, value interface{}) []interface{} {
+	for i, item := range items {
+		size := fingerItemSize(item)
+		if index < size {
+			result := make([]interface{}, len(items))
+			copy(result, items)
+			result[i] = updateFingerItem(item, index, value)
+			return result
+		}
+		index -= size
+	}
+	panic(ErrImpossible)
+}
+
+// Returns a copy of the tree with the element at index, which must
+// be in range, replaced by value
+func updateFingerTree(tree *fingerTree, index int, value interface{}) *fingerTree {
+	if tree.isSingle() {
+		return &fingerTree{
+			size:   tree.size,
+			single: updateFingerItem(tree.single, index, value),
+		}
+	}
+	front, middle, back := tree.front, tree.middle, tree.back
+	frontSize, middleSize := fingerItemsSize(front), middle.treeSize()
+	if index < frontSize {
+		front = updateFingerItems(front, index, value)
+	} else if index < frontSize+middleSize {
+		middle = readyFingerTree(updateFingerTree(middle.force(), index-frontSize, value))
+	} else {
+		back = updateFingerItems(back, index-frontSize-middleSize, value)
+	}
+	return &fingerTree{
+		size:   tree.size,
+		front:  front,
+		middle: middle,
+		back:   back,
+	}
+}
@@ -0,0 +1,666 @@
+package collections
+
+import (
+	"math/bits"
+	"reflect"
+)
+
+// This file contains the hash array mapped trie (HAMT) shared by the
+// hash based collections.
+//
+// The layout follows CHAMP (Steindorfer and Vinju, "Optimizing
+// Hash-Array Mapped Tries for Fast and Lean Immutable JVM Collections").
+// Each branch of the trie has 32 logical slots, but only stores the slots
+// it uses. Two bitmaps record which slots hold an entry inline and which
+// hold a sub-node, and the entries and sub-nodes are packed into two
+// separate arrays in slot order. The position of a slot in either array
+// is the number of bits set below it in the matching bitmap.
+//
+// Keys are placed using the full 64 bit hash, five bits per level.
+// Keys whose hashes are identical share a CollisionNode.
+//
+// Tries are kept in canonical form: below the root, a branch never holds
+// a lone entry and nothing else, because that entry is always moved up
+// into its parent. This keeps lookups short after removals and means
+// equal tries built in different orders have the same shape.
+
+type hashKeyType = uint64
+type bitmapType = uint32
+
+// Each level of the trie consumes the next 5 bits of the hash,
+// starting from the least significant bits at the root (depth 0).
+const bitsPerTrieDepth hashKeyType = 5
+const sizeOfSlices hashKeyType = 32
+const bitMask hashKeyType = sizeOfSlices - 1
+
+// The number of levels it takes to consume every bit of the hash.
+// The last level only has 4 bits left to use. Two hashes that have not
+// diverged by this depth are identical, so their entries can only go
+// in a CollisionNode.
+const maxTrieDepth hashKeyType = (64 + bitsPerTrieDepth - 1) / bitsPerTrieDepth
+
+// Every method that updates the trie takes an owner. A nil owner means
+// the update is persistent: each node on the path is copied. A non-nil
+// owner identifies a TransientHashMap, and nodes stamped with that owner
+// were created by the transient and are not yet visible to anyone else,
+// so they are edited in place instead of copied.
+type HAMTNode interface {
+	set(owner *transientOwner, hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) (HAMTNode, int)
+	get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool)
+	// Returns the node with the key removed, and whether the
+	// key was present.
+	remove(owner *transientOwner, hash hashKeyType, depth hashKeyType, key interface{}) (HAMTNode, bool)
+	// The number of entries in the node and all of its sub-nodes
+	entryCount() int
+}
+
+// Identifies the nodes a transient may edit in place. Never
+// zero sized, so that every owner has a distinct address.
+type transientOwner struct {
+	_ byte
+}
+
+// A SliceNode is a branch of the trie. Slots marked in dataMap hold
+// an entry in entries, and slots marked in nodeMap hold a sub-node
+// in nodes. A slot is never marked in both. The number of entries
+// below the node is cached in size.
+type SliceNode struct {
+	owner   *transientOwner
+	size    int
+	dataMap bitmapType
+	nodeMap bitmapType
+	entries []KeyValueNode
+	nodes   []HAMTNode
+}
+
+// The root of every empty trie
+var emptySliceNode = &SliceNode{}
+
+// A KeyValueNode is a single entry of the trie, stored inline
+// in a SliceNode or a CollisionNode.
+type KeyValueNode struct {
+	originalHash hashKeyType
+	key          interface{}
+	value        interface{}
+}
+
+// A CollisionNode holds every entry whose keys are distinct
+// but share the same full hash. Entries are searched linearly,
+// which is fine because genuine full hash collisions are rare.
+type CollisionNode struct {
+	owner        *transientOwner
+	originalHash hashKeyType
+	entries      []KeyValueNode
+}
+
+func (node *SliceNode) get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool) {
+	bit := getBitForHash(hash, depth)
+	if node.dataMap&bit != 0 {
+		entry := &node.entries[getIndexForBit(node.dataMap, bit)]
+		if entry.originalHash == hash && keysEqual(entry.key, key) {
+			return entry.value, true
+		}
+		return nil, false
+	}
+	if node.nodeMap&bit != 0 {
+		return node.nodes[getIndexForBit(node.nodeMap, bit)].get(hash, depth+1, key)
+	}
+	return nil, false
+}
+
+func (node *SliceNode) set(owner *transientOwner, hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) (HAMTNode, int) {
+	newEntry := KeyValueNode{
+		originalHash: hash,
+		key:          key,
+		value:        value,
+	}
+	bit := getBitForHash(hash, depth)
+	if node.dataMap&bit != 0 {
+		index := getIndexForBit(node.dataMap, bit)
+		entry := node.entries[index]
+		if entry.originalHash == hash && keysEqual(entry.key, key) {
+			if sameValue(entry.value, value) {
+				return node, 0
+			}
+			return node.withEntry(owner, index, newEntry), 0
+		}
+		subNode := mergeEntries(owner, entry, newEntry, depth+1)
+		return node.withEntryMovedToNode(owner, bit, subNode), 1
+	}
+	if node.nodeMap&bit != 0 {
+		index := getIndexForBit(node.nodeMap, bit)
+		target := node.nodes[index]
+		newNode, howManyAdded := target.set(owner, hash, depth+1, key, value)
+		if newNode == target {
+			// Either nothing changed, or a transient edited
+			// the child in place and so owns this node too
+			if howManyAdded != 0 {
+				node.size += howManyAdded
+			}
+			return node, howManyAdded
+		}
+		return node.withNode(owner, index, newNode, howManyAdded), howManyAdded
+	}
+	return node.withEntryInserted(owner, bit, newEntry), 1
+}
+
+func (node *SliceNode) remove(owner *transientOwner, hash hashKeyType, depth hashKeyType, key interface{}) (HAMTNode, bool) {
+	bit := getBitForHash(hash, depth)
+	if node.dataMap&bit != 0 {
+		entry := &node.entries[getIndexForBit(node.dataMap, bit)]
+		if entry.originalHash != hash || !keysEqual(entry.key, key) {
+			return node, false
+		}
+		return node.withEntryRemoved(owner, bit), true
+	}
+	if node.nodeMap&bit != 0 {
+		index := getIndexForBit(node.nodeMap, bit)
+		target := node.nodes[index]
+		newNode, removed := target.remove(owner, hash, depth+1, key)
+		if !removed {
+			return node, false
+		}
+		if entry, ok := singleEntry(newNode); ok {
+			return node.withNodeMovedToEntry(owner, bit, entry), true
+		}
+		if newNode == target {
+			// A transient edited the child in place
+			node.size -= 1
+			return node, true
+		}
+		return node.withNode(owner, index, newNode, -1), true
+	}
+	return node, false
+}
+
+func (node *SliceNode) entryCount() int {
+	return node.size
+}
+
+// Whether the node may be edited in place by owner
+func (node *SliceNode) ownedBy(owner *transientOwner) bool {
+	return owner != nil && node.owner == owner
+}
+
+// Returns the entries to use in a copy of the node made for owner.
+// Copies made for a transient may later be edited in place, so they
+// must not share arrays with any other node.
+func (node *SliceNode) entriesFor(owner *transientOwner) []KeyValueNode {
+	if owner == nil {
+		return node.entries
+	}
+	entries := make([]KeyValueNode, len(node.entries))
+	copy(entries, node.entries)
+	return entries
+}
+
+// Returns the sub-nodes to use in a copy of the node made for owner.
+// See entriesFor.
+func (node *SliceNode) nodesFor(owner *transientOwner) []HAMTNode {
+	if owner == nil {
+		return node.nodes
+	}
+	nodes := make([]HAMTNode, len(node.nodes))
+	copy(nodes, node.nodes)
+	return nodes
+}
+
+// Returns the node with the entry at index replaced
+func (node *SliceNode) withEntry(owner *transientOwner, index int, entry KeyValueNode) *SliceNode {
+	if node.ownedBy(owner) {
+		node.entries[index] = entry
+		return node
+	}
+	entries := make([]KeyValueNode, len(node.entries))
+	copy(entries, node.entries)
+	entries[index] = entry
+	return &SliceNode{
+		owner:   owner,
+		size:    node.size,
+		dataMap: node.dataMap,
+		nodeMap: node.nodeMap,
+		entries: entries,
+		nodes:   node.nodesFor(owner),
+	}
+}
+
+// Returns the node with the sub-node at index replaced by child,
+// which holds sizeDelta more entries than the sub-node it replaces
+func (node *SliceNode) withNode(owner *transientOwner, index int, child HAMTNode, sizeDelta int) *SliceNode {
+	size := node.size + sizeDelta
+	if node.ownedBy(owner) {
+		node.size = size
+		node.nodes[index] = child
+		return node
+	}
+	nodes := make([]HAMTNode, len(node.nodes))
+	copy(nodes, node.nodes)
+	nodes[index] = child
+	return &SliceNode{
+		owner:   owner,
+		size:    size,
+		dataMap: node.dataMap,
+		nodeMap: node.nodeMap,
+		entries: node.entriesFor(owner),
+		nodes:   nodes,
+	}
+}
+
+// Returns the node with a new entry in the empty slot bit
+func (node *SliceNode) withEntryInserted(owner *transientOwner, bit bitmapType, entry KeyValueNode) *SliceNode {
+	dataMap := node.dataMap | bit
+	index := getIndexForBit(dataMap, bit)
+	if node.ownedBy(owner) {
+		node.size += 1
+		node.dataMap = dataMap
+		node.entries = insertEntry(node.entries, index, entry, true)
+		return node
+	}
+	return &SliceNode{
+		owner:   owner,
+		size:    node.size + 1,
+		dataMap: dataMap,
+		nodeMap: node.nodeMap,
+		entries: insertEntry(node.entries, index, entry, false),
+		nodes:   node.nodesFor(owner),
+	}
+}
+
+// Returns the node with the entry in slot bit removed
+func (node *SliceNode) withEntryRemoved(owner *transientOwner, bit bitmapType) *SliceNode {
+	index := getIndexForBit(node.dataMap, bit)
+	if node.ownedBy(owner) {
+		node.size -= 1
+		node.dataMap &^= bit
+		node.entries = removeEntry(node.entries, index, true)
+		return node
+	}
+	return &SliceNode{
+		owner:   owner,
+		size:    node.size - 1,
+		dataMap: node.dataMap &^ bit,
+		nodeMap: node.nodeMap,
+		entries: removeEntry(node.entries, index, false),
+		nodes:   node.nodesFor(owner),
+	}
+}
+
+// Returns the node where the entry in slot bit has been replaced
+// by child, which holds that entry along with others
+func (node *SliceNode) withEntryMovedToNode(owner *transientOwner, bit bitmapType, child HAMTNode) *SliceNode {
+	nodeMap := node.nodeMap | bit
+	entryIndex := getIndexForBit(node.dataMap, bit)
+	nodeIndex := getIndexForBit(nodeMap, bit)
+	size := node.size - 1 + child.entryCount()
+	if node.ownedBy(owner) {
+		node.size = size
+		node.dataMap &^= bit
+		node.nodeMap = nodeMap
+		node.entries = removeEntry(node.entries, entryIndex, true)
+		node.nodes = insertNode(node.nodes, nodeIndex, child, true)
+		return node
+	}
+	return &SliceNode{
+		owner:   owner,
+		size:    size,
+		dataMap: node.dataMap &^ bit,
+		nodeMap: nodeMap,
+		entries: removeEntry(node.entries, entryIndex, false),
+		nodes:   insertNode(node.nodes, nodeIndex, child, false),
+	}
+}
+
+// Returns the node where the sub-node in slot bit has been
+// replaced by entry, the last entry left in that sub-node after
+// a removal
+func (node *SliceNode) withNodeMovedToEntry(owner *transientOwner, bit bitmapType, entry KeyValueNode) *SliceNode {
+	dataMap := node.dataMap | bit
+	entryIndex := getIndexForBit(dataMap, bit)
+	nodeIndex := getIndexForBit(node.nodeMap, bit)
+	size := node.size - 1
+	if node.ownedBy(owner) {
+		node.size = size
+		node.dataMap = dataMap
+		node.nodeMap &^= bit
+		node.entries = insertEntry(node.entries, entryIndex, entry, true)
+		node.nodes = removeNode(node.nodes, nodeIndex, true)
+		return node
+	}
+	return &SliceNode{
+		owner:   owner,
+		size:    size,
+		dataMap: dataMap,
+		nodeMap: node.nodeMap &^ bit,
+		entries: insertEntry(node.entries, entryIndex, entry, false),
+		nodes:   removeNode(node.nodes, nodeIndex, false),
+	}
+}
+
+func (node *CollisionNode) get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool) {
+	if hash != node.originalHash {
+		return nil, false
+	}
+	index := node.indexOf(key)
+	if index < 0 {
+		return nil, false
+	}
+	return node.entries[index].value, true
+}
+
+func (node *CollisionNode) set(owner *transientOwner, hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) (HAMTNode, int) {
+	if hash != node.originalHash {
+		return branchFor(owner, node, node.originalHash, depth).set(owner, hash, depth, key, value)
+	}
+
+	newEntry := KeyValueNode{
+		originalHash: hash,
+		key:          key,
+		value:        value,
+	}
+	index := node.indexOf(key)
+	if index < 0 {
+		return node.withEntries(owner, insertEntry(node.entries, len(node.entries), newEntry, node.ownedBy(owner))), 1
+	}
+	if sameValue(node.entries[index].value, value) {
+		return node, 0
+	}
+	if node.ownedBy(owner) {
+		node.entries[index] = newEntry
+		return node, 0
+	}
+	entries := make([]KeyValueNode, len(node.entries))
+	copy(entries, node.entries)
+	entries[index] = newEntry
+	return node.withEntries(owner, entries), 0
+}
+
+func (node *CollisionNode) remove(owner *transientOwner, hash hashKeyType, depth hashKeyType, key interface{}) (HAMTNode, bool) {
+	if hash != node.originalHash {
+		return node, false
+	}
+	index := node.indexOf(key)
+	if index < 0 {
+		return node, false
+	}
+	return node.withEntries(owner, removeEntry(node.entries, index, node.ownedBy(owner))), true
+}
+
+func (node *CollisionNode) entryCount() int {
+	return len(node.entries)
+}
+
+// Whether the node may be edited in place by owner
+func (node *CollisionNode) ownedBy(owner *transientOwner) bool {
+	return owner != nil && node.owner == owner
+}
+
+// Returns the node with its entries replaced. The entries must
+// not be shared with any other node unless owner is nil.
+func (node *CollisionNode) withEntries(owner *transientOwner, entries []KeyValueNode) *CollisionNode {
+	if node.ownedBy(owner) {
+		node.entries = entries
+		return node
+	}
+	return &CollisionNode{
+		owner:        owner,
+		originalHash: node.originalHash,
+		entries:      entries,
+	}
+}
+
+// Returns the position of the entry with the given key, or -1
+// if the node has no such entry
+func (node *CollisionNode) indexOf(key interface{}) int {
+	for i := range node.entries {
+		if keysEqual(node.entries[i].key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Builds the smallest sub-tree at the given depth holding two
+// entries with distinct keys
+func mergeEntries(owner *transientOwner, first KeyValueNode, second KeyValueNode, depth hashKeyType) HAMTNode {
+	if first.originalHash == second.originalHash || depth >= maxTrieDepth {
+		return &CollisionNode{
+			owner:        owner,
+			originalHash: first.originalHash,
+			entries:      []KeyValueNode{first, second},
+		}
+	}
+	firstBit := getBitForHash(first.originalHash, depth)
+	secondBit := getBitForHash(second.originalHash, depth)
+	if firstBit == secondBit {
+		return &SliceNode{
+			owner:   owner,
+			size:    2,
+			nodeMap: firstBit,
+			nodes:   []HAMTNode{mergeEntries(owner, first, second, depth+1)},
+		}
+	}
+	entries := []KeyValueNode{first, second}
+	if secondBit < firstBit {
+		entries[0], entries[1] = second, first
+	}
+	return &SliceNode{
+		owner:   owner,
+		size:    2,
+		dataMap: firstBit | secondBit,
+		entries: entries,
+	}
+}
+
+// Creates a SliceNode at the given depth whose only child is node.
+// Used when a CollisionNode needs to make room for an entry with a
+// different hash: setting the new entry on the returned branch pushes
+// both down the trie until their hashes diverge.
+func branchFor(owner *transientOwner, node HAMTNode, hash hashKeyType, depth hashKeyType) *SliceNode {
+	return &SliceNode{
+		owner:   owner,
+		size:    node.entryCount(),
+		nodeMap: getBitForHash(hash, depth),
+		nodes:   []HAMTNode{node},
+	}
+}
+
+// If the node holds exactly one entry and nothing else, returns
+// that entry so the parent can store it inline
+func singleEntry(node HAMTNode) (KeyValueNode, bool) {
+	switch node := node.(type) {
+	case *SliceNode:
+		if node.nodeMap == 0 && len(node.entries) == 1 {
+			return node.entries[0], true
+		}
+	case *CollisionNode:
+		if len(node.entries) == 1 {
+			return node.entries[0], true
+		}
+	}
+	return KeyValueNode{}, false
+}
+
+// Returns the slot the hash occupies in a SliceNode at depth
+func getIndexForHash(hash hashKeyType, depth hashKeyType) hashKeyType {
+	return (hash >> (depth * bitsPerTrieDepth)) & bitMask
+}
+
+// Returns the bitmap bit for the slot the hash occupies at depth
+func getBitForHash(hash hashKeyType, depth hashKeyType) bitmapType {
+	return 1 << getIndexForHash(hash, depth)
+}
+
+// Returns the position in a compact array of the slot marked by bit
+func getIndexForBit(bitmap bitmapType, bit bitmapType) int {
+	return bits.OnesCount32(bitmap & (bit - 1))
+}
+
+// Reports whether two keys are equal, deferring to
+// Hashable.Equals when the stored key is Hashable
+func keysEqual(stored interface{}, key interface{}) bool {
+	if hashable, ok := stored.(Hashable); ok {
+		return hashable.Equals(key)
+	}
+	return stored == key
+}
+
+// Reports whether two values are known to be equal. Values that
+// can't be compared with == are never considered the same, so
+// storing them always produces a new node.
+func sameValue(a interface{}, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if !comparableValue(reflect.ValueOf(a)) || !comparableValue(reflect.ValueOf(b)) {
+		return false
+	}
+	return a == b
+}
+
+// Reports whether == can be used on the value without panicking. A
+// comparable type isn't enough, since an interface nested in a struct
+// or array may hold a slice, map or function.
+func comparableValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		return v.IsNil() || comparableValue(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !comparableValue(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !comparableValue(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Map, reflect.Func:
+		return false
+	default:
+		return true
+	}
+}
+
+// Returns entries with entry inserted at index. Unless inPlace
+// is set, entries is left untouched and a new array is returned.
+func insertEntry(entries []KeyValueNode, index int, entry KeyValueNode, inPlace bool) []KeyValueNode {
+	if inPlace {
+		entries = append(entries, KeyValueNode{})
+		copy(entries[index+1:], entries[index:])
+		entries[index] = entry
+		return entries
+	}
+	newEntries := make([]KeyValueNode, len(entries)+1)
+	copy(newEntries, entries[:index])
+	newEntries[index] = entry
+	copy(newEntries[index+1:], entries[index:])
+	return newEntries
+}
+
+// Returns entries with the entry at index removed. Unless inPlace
+// is set, entries is left untouched and a new array is returned.
+func removeEntry(entries []KeyValueNode, index int, inPlace bool) []KeyValueNode {
+	if inPlace {
+		copy(entries[index:], entries[index+1:])
+		entries[len(entries)-1] = KeyValueNode{}
+		return entries[:len(entries)-1]
+	}
+	newEntries := make([]KeyValueNode, len(entries)-1)
+	copy(newEntries, entries[:index])
+	copy(newEntries[index:], entries[index+1:])
+	return newEntries
+}
+
+// Returns nodes with node inserted at index. Unless inPlace
+// is set, nodes is left untouched and a new array is returned.
+func insertNode(nodes []HAMTNode, index int, node HAMTNode, inPlace bool) []HAMTNode {
+	if inPlace {
+		nodes = append(nodes, nil)
+		copy(nodes[index+1:], nodes[index:])
+		nodes[index] = node
+		return nodes
+	}
+	newNodes := make([]HAMTNode, len(nodes)+1)
+	copy(newNodes, nodes[:index])
+	newNodes[index] = node
+	copy(newNodes[index+1:], nodes[index:])
+	return newNodes
+}
+
+// Returns nodes with the node at index removed. Unless inPlace
+// is set, nodes is left untouched and a new array is returned.
+func removeNode(nodes []HAMTNode, index int, inPlace bool) []HAMTNode {
+	if inPlace {
+		copy(nodes[index:], nodes[index+1:])
+		nodes[len(nodes)-1] = nil
+		return nodes[:len(nodes)-1]
+	}
+	newNodes := make([]HAMTNode, len(nodes)-1)
+	copy(newNodes, nodes[:index])
+	copy(newNodes[index:], nodes[index+1:])
+	return newNodes
+}
+
+// A depth first walk over the entries of a trie. Shared by the
+// iterators of every trie backed collection.
+type trieIterator struct {
+	stack []trieIteratorFrame
+	entry *KeyValueNode
+}
+
+// A node on the path of a trieIterator, along with the position
+// of the last entry or sub-node visited in that node. Within a
+// SliceNode, entries are visited before sub-nodes.
+type trieIteratorFrame struct {
+	node  HAMTNode
+	index int
+}
+
+func newTrieIterator(root HAMTNode) *trieIterator {
+	return &trieIterator{
+		stack: []trieIteratorFrame{{node: root, index: -1}},
+	}
+}
+
+func (iterator *trieIterator) MoveNext() bool {
+	for len(iterator.stack) > 0 {
+		top := &iterator.stack[len(iterator.stack)-1]
+		top.index += 1
+		switch node := top.node.(type) {
+		case *SliceNode:
+			if top.index < len(node.entries) {
+				iterator.entry = &node.entries[top.index]
+				return true
+			}
+			nodeIndex := top.index - len(node.entries)
+			if nodeIndex < len(node.nodes) {
+				iterator.stack = append(iterator.stack, trieIteratorFrame{node: node.nodes[nodeIndex], index: -1})
+				continue
+			}
+		case *CollisionNode:
+			if top.index < len(node.entries) {
+				iterator.entry = &node.entries[top.index]
+				return true
+			}
+		default:
+			panic(ErrImpossible)
+		}
+		iterator.stack = iterator.stack[:len(iterator.stack)-1]
+	}
+	iterator.entry = nil
+	return false
+}
+
+func (iterator *trieIterator) current() *KeyValueNode {
+	if iterator.entry == nil {
+		panic(ErrIterationOutOfRange)
+	}
+	return iterator.entry
+}
@@ -0,0 +1,350 @@
+package collections
+
+// Structural algebra over tries.
+//
+// Each function here walks two tries side by side, slot by slot. Both
+// tries must have been built with the same hashing (see sameHashing),
+// so the same key always sits on the same path in both. Sub-trees that
+// only one side has, or that both sides share, are reused or skipped
+// without being looked into, so the cost depends on how much the tries
+// differ rather than on how large they are.
+//
+// The functions take two nodes at the same depth, and return a node in
+// canonical form, or nil when no entries are left. Results reuse the
+// left node whenever nothing about it changed.
+
+// Chooses the entry to keep when both tries hold the same key. Returning
+// left unchanged lets the result share the left trie. A nil resolver
+// keeps the left entry, which also lets a sub-tree that both tries
+// share be reused without looking into it.
+type entryResolver func(left *KeyValueNode, right *KeyValueNode) *KeyValueNode
+
+func (resolve entryResolver) apply(left *KeyValueNode, right *KeyValueNode) *KeyValueNode {
+	if resolve == nil {
+		return left
+	}
+	return resolve(left, right)
+}
+
+// The contents of one slot of a SliceNode: an entry, a sub-node or
+// nothing
+type trieSlot struct {
+	entry *KeyValueNode
+	node  HAMTNode
+}
+
+func (node *SliceNode) slot(bit bitmapType) trieSlot {
+	if node.dataMap&bit != 0 {
+		return trieSlot{entry: &node.entries[getIndexForBit(node.dataMap, bit)]}
+	}
+	if node.nodeMap&bit != 0 {
+		return trieSlot{node: node.nodes[getIndexForBit(node.nodeMap, bit)]}
+	}
+	return trieSlot{}
+}
+
+func (slot trieSlot) isEmpty() bool {
+	return slot.entry == nil && slot.node == nil
+}
+
+// Accumulates the slots of a new SliceNode in increasing slot order
+type sliceNodeBuilder struct {
+	node SliceNode
+}
+
+func (builder *sliceNodeBuilder) add(bit bitmapType, slot trieSlot) {
+	node := &builder.node
+	if slot.node != nil {
+		if entry, ok := singleEntry(slot.node); ok {
+			slot = trieSlot{entry: &entry}
+		}
+	}
+	if slot.entry != nil {
+		node.dataMap |= bit
+		node.entries = append(node.entries, *slot.entry)
+		node.size += 1
+	} else if slot.node != nil {
+		node.nodeMap |= bit
+		node.nodes = append(node.nodes, slot.node)
+		node.size += slot.node.entryCount()
+	}
+}
+
+// Returns the built node, or nil if it has no entries
+func (builder *sliceNodeBuilder) build() HAMTNode {
+	if builder.node.size == 0 {
+		return nil
+	}
+	node := builder.node
+	return &node
+}
+
+// Returns the lowest bit set in the bitmap
+func lowestBit(bitmap bitmapType) bitmapType {
+	return bitmap & -bitmap
+}
+
+// Returns the entry for the key in the trie rooted at node, or nil
+func findEntry(node HAMTNode, hash hashKeyType, depth hashKeyType, key interface{}) *KeyValueNode {
+	for {
+		switch current := node.(type) {
+		case *SliceNode:
+			bit := getBitForHash(hash, depth)
+			if current.dataMap&bit != 0 {
+				entry := &current.entries[getIndexForBit(current.dataMap, bit)]
+				if entry.originalHash == hash && keysEqual(entry.key, key) {
+					return entry
+				}
+				return nil
+			}
+			if current.nodeMap&bit == 0 {
+				return nil
+			}
+			node = current.nodes[getIndexForBit(current.nodeMap, bit)]
+			depth += 1
+		case *CollisionNode:
+			if current.originalHash != hash {
+				return nil
+			}
+			index := current.indexOf(key)
+			if index < 0 {
+				return nil
+			}
+			return &current.entries[index]
+		default:
+			panic(ErrImpossible)
+		}
+	}
+}
+
+// Calls iterFn on every entry in the trie rooted at node
+func forEachEntry(node HAMTNode, iterFn func(entry *KeyValueNode)) {
+	iterator := newTrieIterator(node)
+	for iterator.MoveNext() {
+		iterFn(iterator.current())
+	}
+}
+
+// Returns a trie with the entries of both tries. Where both hold
+// a key, resolve chooses the entry to keep.
+func unionNodes(left HAMTNode, right HAMTNode, depth hashKeyType, resolve entryResolver) HAMTNode {
+	if left == right && resolve == nil {
+		return left
+	}
+	leftSlice, leftOk := left.(*SliceNode)
+	rightSlice, rightOk := right.(*SliceNode)
+	if !leftOk || !rightOk {
+		return unionByInsertion(left, right, depth, resolve)
+	}
+
+	builder := sliceNodeBuilder{}
+	unchanged := true
+	occupied := leftSlice.dataMap | leftSlice.nodeMap | rightSlice.dataMap | rightSlice.nodeMap
+	for ; occupied != 0; occupied &^= lowestBit(occupied) {
+		bit := lowestBit(occupied)
+		leftSlot, rightSlot := leftSlice.slot(bit), rightSlice.slot(bit)
+		result := leftSlot
+		switch {
+		case rightSlot.isEmpty():
+		case leftSlot.isEmpty():
+			result = rightSlot
+		case leftSlot.entry != nil && rightSlot.entry != nil:
+			if sameKey(leftSlot.entry, rightSlot.entry) {
+				result = trieSlot{entry: resolve.apply(leftSlot.entry, rightSlot.entry)}
+			} else {
+				result = trieSlot{node: mergeEntries(nil, *leftSlot.entry, *rightSlot.entry, depth+1)}
+			}
+		case leftSlot.entry != nil:
+			entry := leftSlot.entry
+			if existing := findEntry(rightSlot.node, entry.originalHash, depth+1, entry.key); existing != nil {
+				entry = resolve.apply(entry, existing)
+			}
+			result = trieSlot{node: setEntry(rightSlot.node, depth+1, entry)}
+		case rightSlot.entry != nil:
+			entry := rightSlot.entry
+			if existing := findEntry(leftSlot.node, entry.originalHash, depth+1, entry.key); existing != nil {
+				entry = resolve.apply(existing, entry)
+			}
+			result = trieSlot{node: setEntry(leftSlot.node, depth+1, entry)}
+		default:
+			result = trieSlot{node: unionNodes(leftSlot.node, rightSlot.node, depth+1, resolve)}
+		}
+		unchanged = unchanged && result == leftSlot
+		builder.add(bit, result)
+	}
+	if unchanged {
+		return left
+	}
+	return builder.build()
+}
+
+// The fallback for unionNodes, which inserts the entries of the right
+// trie into the left one by one. Used where the two tries don't line
+// up slot by slot because one side is a CollisionNode.
+func unionByInsertion(left HAMTNode, right HAMTNode, depth hashKeyType, resolve entryResolver) HAMTNode {
+	result := left
+	forEachEntry(right, func(entry *KeyValueNode) {
+		if existing := findEntry(left, entry.originalHash, depth, entry.key); existing != nil {
+			entry = resolve.apply(existing, entry)
+		}
+		result = setEntry(result, depth, entry)
+	})
+	return result
+}
+
+// Returns the trie at depth with entry set in it
+func setEntry(node HAMTNode, depth hashKeyType, entry *KeyValueNode) HAMTNode {
+	result, _ := node.set(nil, entry.originalHash, depth, entry.key, entry.value)
+	return result
+}
+
+// Returns a trie with the entries of the left trie whose keys
+// are also in the right trie
+func intersectNodes(left HAMTNode, right HAMTNode, depth hashKeyType) HAMTNode {
+	if left == right {
+		return left
+	}
+	leftSlice, leftOk := left.(*SliceNode)
+	rightSlice, rightOk := right.(*SliceNode)
+	if !leftOk || !rightOk {
+		return filterNodes(left, depth, func(entry *KeyValueNode) bool {
+			return findEntry(right, entry.originalHash, depth, entry.key) != nil
+		})
+	}
+
+	builder := sliceNodeBuilder{}
+	unchanged := true
+	occupied := leftSlice.dataMap | leftSlice.nodeMap
+	for ; occupied != 0; occupied &^= lowestBit(occupied) {
+		bit := lowestBit(occupied)
+		leftSlot, rightSlot := leftSlice.slot(bit), rightSlice.slot(bit)
+		result := trieSlot{}
+		switch {
+		case rightSlot.isEmpty():
+		case leftSlot.entry != nil && rightSlot.entry != nil:
+			if sameKey(leftSlot.entry, rightSlot.entry) {
+				result = leftSlot
+			}
+		case leftSlot.entry != nil:
+			if findEntry(rightSlot.node, leftSlot.entry.originalHash, depth+1, leftSlot.entry.key) != nil {
+				result = leftSlot
+			}
+		case rightSlot.entry != nil:
+			result.entry = findEntry(leftSlot.node, rightSlot.entry.originalHash, depth+1, rightSlot.entry.key)
+		default:
+			result.node = intersectNodes(leftSlot.node, rightSlot.node, depth+1)
+		}
+		unchanged = unchanged && result == leftSlot
+		builder.add(bit, result)
+	}
+	if unchanged {
+		return left
+	}
+	return builder.build()
+}
+
+// Returns a trie with the entries of the left trie whose keys
+// are not in the right trie
+func differenceNodes(left HAMTNode, right HAMTNode, depth hashKeyType) HAMTNode {
+	if left == right {
+		return nil
+	}
+	leftSlice, leftOk := left.(*SliceNode)
+	rightSlice, rightOk := right.(*SliceNode)
+	if !leftOk || !rightOk {
+		return filterNodes(left, depth, func(entry *KeyValueNode) bool {
+			return findEntry(right, entry.originalHash, depth, entry.key) == nil
+		})
+	}
+
+	builder := sliceNodeBuilder{}
+	unchanged := true
+	occupied := leftSlice.dataMap | leftSlice.nodeMap
+	for ; occupied != 0; occupied &^= lowestBit(occupied) {
+		bit := lowestBit(occupied)
+		leftSlot, rightSlot := leftSlice.slot(bit), rightSlice.slot(bit)
+		result := leftSlot
+		switch {
+		case rightSlot.isEmpty():
+		case leftSlot.entry != nil && rightSlot.entry != nil:
+			if sameKey(leftSlot.entry, rightSlot.entry) {
+				result = trieSlot{}
+			}
+		case leftSlot.entry != nil:
+			if findEntry(rightSlot.node, leftSlot.entry.originalHash, depth+1, leftSlot.entry.key) != nil {
+				result = trieSlot{}
+			}
+		case rightSlot.entry != nil:
+			result.node, _ = leftSlot.node.remove(nil, rightSlot.entry.originalHash, depth+1, rightSlot.entry.key)
+		default:
+			result.node = differenceNodes(leftSlot.node, rightSlot.node, depth+1)
+		}
+		unchanged = unchanged && result == leftSlot
+		builder.add(bit, result)
+	}
+	if unchanged {
+		return left
+	}
+	return builder.build()
+}
+
+// Reports whether every key of the left trie is in the right trie
+func subsetNodes(left HAMTNode, right HAMTNode, depth hashKeyType) bool {
+	if left == right {
+		return true
+	}
+	if left.entryCount() > right.entryCount() {
+		return false
+	}
+	leftSlice, leftOk := left.(*SliceNode)
+	rightSlice, rightOk := right.(*SliceNode)
+	if !leftOk || !rightOk {
+		return filterNodes(left, depth, func(entry *KeyValueNode) bool {
+			return findEntry(right, entry.originalHash, depth, entry.key) == nil
+		}) == nil
+	}
+
+	occupied := leftSlice.dataMap | leftSlice.nodeMap
+	for ; occupied != 0; occupied &^= lowestBit(occupied) {
+		bit := lowestBit(occupied)
+		leftSlot, rightSlot := leftSlice.slot(bit), rightSlice.slot(bit)
+		var contained bool
+		switch {
+		case rightSlot.isEmpty():
+			contained = false
+		case leftSlot.entry != nil && rightSlot.entry != nil:
+			contained = sameKey(leftSlot.entry, rightSlot.entry)
+		case leftSlot.entry != nil:
+			contained = findEntry(rightSlot.node, leftSlot.entry.originalHash, depth+1, leftSlot.entry.key) != nil
+		case rightSlot.entry != nil:
+			// A sub-node always holds more than one entry
+			contained = false
+		default:
+			contained = subsetNodes(leftSlot.node, rightSlot.node, depth+1)
+		}
+		if !contained {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a trie at depth with the entries of node for which
+// keepFn returns true, or nil if there are none
+func filterNodes(node HAMTNode, depth hashKeyType, keepFn func(entry *KeyValueNode) bool) HAMTNode {
+	var result HAMTNode = node
+	forEachEntry(node, func(entry *KeyValueNode) {
+		if !keepFn(entry) {
+			result, _ = result.remove(nil, entry.originalHash, depth, entry.key)
+		}
+	})
+	if result.entryCount() == 0 {
+		return nil
+	}
+	return result
+}
+
+// Whether two entries are for the same key
+func sameKey(first *KeyValueNode, second *KeyValueNode) bool {
+	return first.originalHash == second.originalHash && keysEqual(first.key, second.key)
+}
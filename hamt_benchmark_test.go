@@ -0,0 +1,162 @@
+package collections
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// Benchmarks comparing the compact trie against the previous layout,
+// where every branch allocated all 32 slots and every update copied
+// all of them. The previous layout is kept below, trimmed to Set and
+// Get, purely so the two can be measured side by side.
+
+type legacySliceNode struct {
+	data []legacyNode
+}
+
+type legacyKeyValueNode struct {
+	originalHash hashKeyType
+	key          interface{}
+	value        interface{}
+}
+
+type legacyNode interface {
+	set(hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) legacyNode
+	get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool)
+}
+
+func (node *legacyKeyValueNode) get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool) {
+	if hash != node.originalHash || key != node.key {
+		return nil, false
+	}
+	return node.value, true
+}
+
+func (node *legacyKeyValueNode) set(hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) legacyNode {
+	if node.originalHash == hash {
+		return &legacyKeyValueNode{originalHash: hash, key: key, value: value}
+	}
+	data := make([]legacyNode, sizeOfSlices)
+	data[getIndexForHash(node.originalHash, depth)] = node
+	return (&legacySliceNode{data: data}).set(hash, depth, key, value)
+}
+
+func (node *legacySliceNode) get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool) {
+	target := node.data[getIndexForHash(hash, depth)]
+	if target == nil {
+		return nil, false
+	}
+	return target.get(hash, depth+1, key)
+}
+
+func (node *legacySliceNode) set(hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) legacyNode {
+	index := getIndexForHash(hash, depth)
+	data := make([]legacyNode, sizeOfSlices)
+	copy(data, node.data)
+	if data[index] == nil {
+		data[index] = &legacyKeyValueNode{originalHash: hash, key: key, value: value}
+	} else {
+		data[index] = data[index].set(hash, depth+1, key, value)
+	}
+	return &legacySliceNode{data: data}
+}
+
+var benchmarkSizes = []int{10, 1000, 100000}
+
+func benchmarkKeys(count int) []interface{} {
+	keys := make([]interface{}, count)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	return keys
+}
+
+func buildCompactTrie(keys []interface{}, seed hashSeed) HAMTNode {
+	var root HAMTNode = emptySliceNode
+	for _, key := range keys {
+		root, _ = root.set(nil, getHash(key, seed), 0, key, key)
+	}
+	return root
+}
+
+func buildLegacyTrie(keys []interface{}, seed hashSeed) legacyNode {
+	var root legacyNode = &legacySliceNode{data: make([]legacyNode, sizeOfSlices)}
+	for _, key := range keys {
+		root = root.set(getHash(key, seed), 0, key, key)
+	}
+	return root
+}
+
+// Reports the heap retained by the trie built by build, per entry
+func reportBytesPerEntry(b *testing.B, count int, build func() interface{}) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	trie := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(trie)
+	b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(count), "bytes/entry")
+}
+
+func BenchmarkHAMTMemory(b *testing.B) {
+	seed := randomHashSeed()
+	for _, size := range benchmarkSizes {
+		keys := benchmarkKeys(size)
+		b.Run(fmt.Sprintf("compact/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				reportBytesPerEntry(b, size, func() interface{} { return buildCompactTrie(keys, seed) })
+			}
+		})
+		b.Run(fmt.Sprintf("legacy/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				reportBytesPerEntry(b, size, func() interface{} { return buildLegacyTrie(keys, seed) })
+			}
+		})
+	}
+}
+
+func BenchmarkHAMTSet(b *testing.B) {
+	seed := randomHashSeed()
+	for _, size := range benchmarkSizes {
+		keys := benchmarkKeys(size)
+		b.Run(fmt.Sprintf("compact/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buildCompactTrie(keys, seed)
+			}
+		})
+		b.Run(fmt.Sprintf("legacy/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buildLegacyTrie(keys, seed)
+			}
+		})
+	}
+}
+
+func BenchmarkHAMTGet(b *testing.B) {
+	seed := randomHashSeed()
+	for _, size := range benchmarkSizes {
+		keys := benchmarkKeys(size)
+		hashes := make([]hashKeyType, size)
+		for i, key := range keys {
+			hashes[i] = getHash(key, seed)
+		}
+		compact := buildCompactTrie(keys, seed)
+		legacy := buildLegacyTrie(keys, seed)
+		b.Run(fmt.Sprintf("compact/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				j := i % size
+				compact.get(hashes[j], 0, keys[j])
+			}
+		})
+		b.Run(fmt.Sprintf("legacy/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				j := i % size
+				legacy.get(hashes[j], 0, keys[j])
+			}
+		})
+	}
+}
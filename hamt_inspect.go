@@ -0,0 +1,240 @@
+package collections
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// This file contains tools for looking inside the trie of a HashMap
+// when debugging: statistics about its shape, a check of the invariants
+// described in hamt.go, and a Graphviz rendering.
+
+// HashMapStats describes the shape of the trie behind a HashMap.
+// See HashMap.Stats.
+type HashMapStats struct {
+	// The number of entries stored at each depth, starting from the
+	// root at depth 0. Entries in a CollisionNode count at the depth
+	// of the CollisionNode.
+	EntriesByDepth []int
+	SliceNodes     int
+	CollisionNodes int
+	// The mean number of occupied slots per SliceNode, counting both
+	// inline entries and sub-nodes, out of a possible 32
+	AverageFill float64
+	// The number of entries in each CollisionNode
+	CollisionSizes []int
+}
+
+// Returns statistics about the shape of the map's trie. Walks the
+// whole trie, so it is O(n).
+func (hashMap *HashMap) Stats() HashMapStats {
+	stats := HashMapStats{}
+	occupiedSlots := 0
+	var visit func(node HAMTNode, depth int)
+	visit = func(node HAMTNode, depth int) {
+		for len(stats.EntriesByDepth) <= depth {
+			stats.EntriesByDepth = append(stats.EntriesByDepth, 0)
+		}
+		switch node := node.(type) {
+		case *SliceNode:
+			stats.SliceNodes += 1
+			occupiedSlots += len(node.entries) + len(node.nodes)
+			stats.EntriesByDepth[depth] += len(node.entries)
+			for _, child := range node.nodes {
+				visit(child, depth+1)
+			}
+		case *CollisionNode:
+			stats.CollisionNodes += 1
+			stats.EntriesByDepth[depth] += len(node.entries)
+			stats.CollisionSizes = append(stats.CollisionSizes, len(node.entries))
+		}
+	}
+	visit(hashMap.root, 0)
+	if stats.SliceNodes > 0 {
+		stats.AverageFill = float64(occupiedSlots) / float64(stats.SliceNodes)
+	}
+	return stats
+}
+
+// Checks the invariants of the map's trie, and returns an error
+// wrapping ErrInvalidTrie that describes the first one broken, or nil
+// if the trie is sound. The checks are:
+//   - the size of the map, and the size cached in every SliceNode,
+//     match the number of entries below them
+//   - the bitmaps of every SliceNode agree with its arrays, and no
+//     slot holds both an entry and a sub-node
+//   - every entry is in the slot its hash selects at every level,
+//     and its stored hash is still the hash of its key
+//   - below the root, no SliceNode is empty or holds a lone entry,
+//     and every CollisionNode holds at least two distinct keys
+//     sharing one hash
+//
+// Walks the whole trie and rehashes every key, so it is O(n).
+func (hashMap *HashMap) Validate() error {
+	validator := trieValidator{hasher: hashMap.hasher}
+	count, err := validator.validate(hashMap.root, 0, 0, nil)
+	if err != nil {
+		return err
+	}
+	if count != hashMap.size {
+		return invalidTrie(nil, "map size is %d but the trie holds %d entries", hashMap.size, count)
+	}
+	return nil
+}
+
+type trieValidator struct {
+	hasher *keyHasher
+}
+
+// Validates the node at depth, reached through the slots in path,
+// and returns the number of entries below it. Every hash stored
+// below the node must match prefix in the bits consumed so far.
+func (validator trieValidator) validate(node HAMTNode, depth hashKeyType, prefix hashKeyType, path []int) (int, error) {
+	switch node := node.(type) {
+	case *SliceNode:
+		return validator.validateSliceNode(node, depth, prefix, path)
+	case *CollisionNode:
+		return validator.validateCollisionNode(node, depth, prefix, path)
+	case nil:
+		return 0, invalidTrie(path, "missing node")
+	}
+	return 0, invalidTrie(path, "unknown node type %T", node)
+}
+
+func (validator trieValidator) validateSliceNode(node *SliceNode, depth hashKeyType, prefix hashKeyType, path []int) (int, error) {
+	if depth >= maxTrieDepth {
+		return 0, invalidTrie(path, "SliceNode below the last level of the trie")
+	}
+	if node.dataMap&node.nodeMap != 0 {
+		return 0, invalidTrie(path, "slots %032b hold both an entry and a sub-node", node.dataMap&node.nodeMap)
+	}
+	if len(node.entries) != bits.OnesCount32(node.dataMap) {
+		return 0, invalidTrie(path, "%d entries for data map %032b", len(node.entries), node.dataMap)
+	}
+	if len(node.nodes) != bits.OnesCount32(node.nodeMap) {
+		return 0, invalidTrie(path, "%d sub-nodes for node map %032b", len(node.nodes), node.nodeMap)
+	}
+	if depth > 0 {
+		if len(node.entries) == 0 && len(node.nodes) == 0 {
+			return 0, invalidTrie(path, "empty SliceNode")
+		}
+		if len(node.entries) == 1 && len(node.nodes) == 0 {
+			return 0, invalidTrie(path, "SliceNode holds a lone entry that belongs in its parent")
+		}
+	}
+	count := 0
+	for slot := 0; slot < int(sizeOfSlices); slot++ {
+		bit := bitmapType(1) << slot
+		slotPrefix := prefix | hashKeyType(slot)<<(depth*bitsPerTrieDepth)
+		slotPath := append(path[:len(path):len(path)], slot)
+		if node.dataMap&bit != 0 {
+			entry := &node.entries[getIndexForBit(node.dataMap, bit)]
+			if err := validator.validateEntry(entry, depth+1, slotPrefix, slotPath); err != nil {
+				return 0, err
+			}
+			count += 1
+		}
+		if node.nodeMap&bit != 0 {
+			childCount, err := validator.validate(node.nodes[getIndexForBit(node.nodeMap, bit)], depth+1, slotPrefix, slotPath)
+			if err != nil {
+				return 0, err
+			}
+			count += childCount
+		}
+	}
+	if count != node.size {
+		return 0, invalidTrie(path, "SliceNode size is %d but it holds %d entries", node.size, count)
+	}
+	return count, nil
+}
+
+func (validator trieValidator) validateCollisionNode(node *CollisionNode, depth hashKeyType, prefix hashKeyType, path []int) (int, error) {
+	if depth == 0 {
+		return 0, invalidTrie(path, "CollisionNode at the root")
+	}
+	if len(node.entries) < 2 {
+		return 0, invalidTrie(path, "CollisionNode holds %d entries", len(node.entries))
+	}
+	for i := range node.entries {
+		entry := &node.entries[i]
+		if entry.originalHash != node.originalHash {
+			return 0, invalidTrie(path, "entry with hash %#x in CollisionNode for hash %#x", entry.originalHash, node.originalHash)
+		}
+		if err := validator.validateEntry(entry, depth, prefix, path); err != nil {
+			return 0, err
+		}
+		for j := 0; j < i; j++ {
+			if keysEqual(node.entries[j].key, entry.key) {
+				return 0, invalidTrie(path, "CollisionNode holds key %v twice", entry.key)
+			}
+		}
+	}
+	return len(node.entries), nil
+}
+
+// Checks that the entry's hash matches prefix in the bits used
+// by the first levels of the trie
+func (validator trieValidator) validateEntry(entry *KeyValueNode, levels hashKeyType, prefix hashKeyType, path []int) error {
+	// Shifting by 64 or more gives 0, so the mask covers every
+	// bit once the levels have used them all
+	mask := hashKeyType(1)<<(levels*bitsPerTrieDepth) - 1
+	if entry.originalHash&mask != prefix {
+		return invalidTrie(path, "entry for key %v with hash %#x is in the wrong slot", entry.key, entry.originalHash)
+	}
+	if hash := validator.hasher.hash(entry.key); hash != entry.originalHash {
+		return invalidTrie(path, "key %v was stored with hash %#x but now hashes to %#x", entry.key, entry.originalHash, hash)
+	}
+	return nil
+}
+
+func invalidTrie(path []int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: at slots %v: %s", ErrInvalidTrie, path, fmt.Sprintf(format, args...))
+}
+
+// Returns a description of the map's trie in the Graphviz DOT
+// language, with a box for every node and an ellipse for every
+// entry. Edges are labeled with the slot they come from. Meant
+// for small maps: the output has a line for every entry.
+func (hashMap *HashMap) DOT() string {
+	var builder strings.Builder
+	builder.WriteString("digraph HashMap {\n")
+	ids := 0
+	nextID := func() string {
+		ids += 1
+		return "n" + strconv.Itoa(ids-1)
+	}
+	writeEntry := func(parent string, edgeLabel string, entry *KeyValueNode) {
+		id := nextID()
+		fmt.Fprintf(&builder, "  %s [shape=ellipse, label=%s];\n", id, strconv.Quote(fmt.Sprintf("%v: %v", entry.key, entry.value)))
+		fmt.Fprintf(&builder, "  %s -> %s [label=%s];\n", parent, id, strconv.Quote(edgeLabel))
+	}
+	var writeNode func(node HAMTNode) string
+	writeNode = func(node HAMTNode) string {
+		id := nextID()
+		switch node := node.(type) {
+		case *SliceNode:
+			fmt.Fprintf(&builder, "  %s [shape=box, label=\"SliceNode\\nsize %d\"];\n", id, node.size)
+			for slot := 0; slot < int(sizeOfSlices); slot++ {
+				bit := bitmapType(1) << slot
+				if node.dataMap&bit != 0 {
+					writeEntry(id, strconv.Itoa(slot), &node.entries[getIndexForBit(node.dataMap, bit)])
+				}
+				if node.nodeMap&bit != 0 {
+					child := writeNode(node.nodes[getIndexForBit(node.nodeMap, bit)])
+					fmt.Fprintf(&builder, "  %s -> %s [label=\"%d\"];\n", id, child, slot)
+				}
+			}
+		case *CollisionNode:
+			fmt.Fprintf(&builder, "  %s [shape=box, label=\"CollisionNode\\nhash %#x\"];\n", id, node.originalHash)
+			for i := range node.entries {
+				writeEntry(id, "", &node.entries[i])
+			}
+		}
+		return id
+	}
+	writeNode(hashMap.root)
+	builder.WriteString("}\n")
+	return builder.String()
+}
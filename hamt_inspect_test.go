@@ -0,0 +1,132 @@
+package collections
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashMapStats(t *testing.T) {
+	expect := expectFor(t)
+	constantHash := func(key interface{}, seed hashSeed) hashKeyType {
+		if s, ok := key.(string); ok {
+			return hashKeyType(len(s)) << 5
+		}
+		return key.(uint64)
+	}
+	m := newHashMapWithHashFn(constantHash).
+		set(uint64(1), 1).
+		set(uint64(3|1<<5), 2).
+		set(uint64(3|2<<5), 3).
+		set("a", 4).
+		set("b", 5).
+		set("c", 6)
+
+	stats := m.Stats()
+	expect(stats.EntriesByDepth).ToDeepEqual([]int{1, 5})
+	expect(stats.SliceNodes).ToBe(2)
+	expect(stats.CollisionNodes).ToBe(1)
+	expect(stats.CollisionSizes).ToDeepEqual([]int{3})
+	// The root holds the entry for 1, the branch for slot 3 and the
+	// CollisionNode, and the branch holds two entries
+	expect(stats.AverageFill).ToBe(float64(3+2) / 2)
+
+	empty := NewHashMap().Stats()
+	expect(empty.EntriesByDepth).ToDeepEqual([]int{0})
+	expect(empty.SliceNodes).ToBe(1)
+	expect(empty.AverageFill).ToBe(0.0)
+}
+
+func TestHashMapValidateAcceptsUpdatedMaps(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	for name, hashers := range hashersUnderTest() {
+		m := newHashMapWithHasher(hashers[0])
+		transient := newHashMapWithHasher(hashers[0]).Transient()
+		for i := 0; i < 2000; i++ {
+			key := random.rand.Intn(300)
+			if random.rand.Intn(3) == 0 {
+				m = m.remove(key)
+				transient.Remove(key)
+			} else {
+				m = m.set(key, i)
+				transient.Set(key, i)
+			}
+			if i%100 == 0 {
+				if err := m.Validate(); err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+			}
+		}
+		expect(m.Validate()).ToBe(nil)
+		expect(transient.Persistent().Validate()).ToBe(nil)
+	}
+}
+
+func TestHashMapValidateFindsBrokenTries(t *testing.T) {
+	expect := expectFor(t)
+	build := func() *HashMap {
+		return newHashMapWithHashFn(identityHash).
+			set(uint64(1), 1).
+			set(uint64(1|1<<5), 2).
+			set(uint64(2|1<<5), 3)
+	}
+	branch := func(m *HashMap) *SliceNode {
+		return m.root.(*SliceNode).nodes[0].(*SliceNode)
+	}
+	corruptions := map[string]func(m *HashMap){
+		"map size": func(m *HashMap) {
+			m.size = 4
+		},
+		"node size": func(m *HashMap) {
+			branch(m).size = 3
+		},
+		"wrong slot": func(m *HashMap) {
+			branch(m).entries[0].originalHash = 1 | 2<<5
+		},
+		"changed hash": func(m *HashMap) {
+			branch(m).entries[0].key = uint64(5)
+		},
+		"bitmaps": func(m *HashMap) {
+			branch(m).dataMap |= 1 << 9
+		},
+		"lone entry": func(m *HashMap) {
+			node := branch(m)
+			node.dataMap = 1 << 0
+			node.entries = node.entries[:1]
+			node.size = 1
+			m.root.(*SliceNode).size = 2
+			m.size = 2
+		},
+		"empty node": func(m *HashMap) {
+			node := branch(m)
+			node.dataMap = 0
+			node.entries = nil
+			node.size = 0
+			m.root.(*SliceNode).size = 0
+			m.size = 0
+		},
+	}
+	for name, corrupt := range corruptions {
+		m := build()
+		expect(m.Validate()).ToBe(nil)
+		corrupt(m)
+		err := m.Validate()
+		if !errors.Is(err, ErrInvalidTrie) {
+			t.Fatalf("%s: expected an invalid trie, got %v", name, err)
+		}
+	}
+}
+
+func TestHashMapDOT(t *testing.T) {
+	expect := expectFor(t)
+	m := newHashMapWithHashFn(identityHash).set(uint64(1), "one").set(uint64(1|1<<5), "two")
+	dot := m.DOT()
+	expect(strings.HasPrefix(dot, "digraph HashMap {\n")).ToBe(true)
+	expect(strings.HasSuffix(dot, "}\n")).ToBe(true)
+	expect(strings.Contains(dot, "n0 [shape=box, label=\"SliceNode\\nsize 2\"];")).ToBe(true)
+	expect(strings.Contains(dot, "n0 -> n1 [label=\"1\"];")).ToBe(true)
+	expect(strings.Contains(dot, "n2 [shape=ellipse, label=\"1: one\"];")).ToBe(true)
+	expect(strings.Contains(dot, "n1 -> n3 [label=\"1\"];")).ToBe(true)
+	expect(strings.Count(dot, "->")).ToBe(3)
+}
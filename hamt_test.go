@@ -0,0 +1,105 @@
+package collections
+
+import (
+	"math/bits"
+	"testing"
+)
+
+// Uses uint64 keys as their own hash, so tests control where
+// every key lands in the trie
+func identityHash(key interface{}, seed hashSeed) hashKeyType {
+	return key.(uint64)
+}
+
+func TestGetIndexForHash(t *testing.T) {
+	expect := expectFor(t)
+	hash := hashKeyType(1 | 2<<5 | 3<<10 | 31<<15)
+	expect(getIndexForHash(hash, 0)).ToBe(hashKeyType(1))
+	expect(getIndexForHash(hash, 1)).ToBe(hashKeyType(2))
+	expect(getIndexForHash(hash, 2)).ToBe(hashKeyType(3))
+	expect(getIndexForHash(hash, 3)).ToBe(hashKeyType(31))
+	expect(getIndexForHash(hash, 4)).ToBe(hashKeyType(0))
+
+	top := hashKeyType(0xF) << 60
+	expect(getIndexForHash(top, 11)).ToBe(hashKeyType(0))
+	expect(getIndexForHash(top, 12)).ToBe(hashKeyType(15))
+	expect(maxTrieDepth).ToBe(hashKeyType(13))
+}
+
+func TestTrieSpreadsKeysAcrossRootSlots(t *testing.T) {
+	expect := expectFor(t)
+	var m Map = newHashMapWithHashFn(identityHash)
+	for i := uint64(0); i < 32; i++ {
+		m = m.Set(i, i)
+	}
+	root := m.(*HashMap).root.(*SliceNode)
+	expect(root.dataMap).ToBe(bitmapType(0xFFFFFFFF))
+	expect(root.nodeMap).ToBe(bitmapType(0))
+	for i := range root.entries {
+		expect(root.entries[i].key).ToBe(uint64(i))
+	}
+}
+
+func TestTrieKeysDifferingInLowestBit(t *testing.T) {
+	expect := expectFor(t)
+	m := newHashMapWithHashFn(identityHash).set(uint64(2), "two").set(uint64(3), "three")
+	root := m.root.(*SliceNode)
+	expect(root.dataMap).ToBe(bitmapType(1<<2 | 1<<3))
+	val, _ := m.Get(uint64(3))
+	expect(val).ToBe("three")
+}
+
+func TestTrieBranchesOnNextFragment(t *testing.T) {
+	expect := expectFor(t)
+	first := uint64(7 | 1<<5)
+	second := uint64(7 | 2<<5)
+	m := newHashMapWithHashFn(identityHash).set(first, 1).set(second, 2)
+	root := m.root.(*SliceNode)
+	expect(root.dataMap).ToBe(bitmapType(0))
+	expect(root.nodeMap).ToBe(bitmapType(1 << 7))
+	child := root.nodes[0].(*SliceNode)
+	expect(child.dataMap).ToBe(bitmapType(1<<1 | 1<<2))
+	expect(child.entries[0].key).ToBe(first)
+	expect(child.entries[1].key).ToBe(second)
+}
+
+func TestTrieUsesEveryHashBit(t *testing.T) {
+	expect := expectFor(t)
+	first := uint64(1) << 63
+	second := uint64(1) << 62
+	m := newHashMapWithHashFn(identityHash).set(first, 1).set(second, 2).set(uint64(0), 0)
+
+	// The keys share every fragment except the last, so they
+	// only split at the deepest level
+	var node HAMTNode = m.root
+	depth := hashKeyType(0)
+	for {
+		sliceNode := node.(*SliceNode)
+		if len(sliceNode.nodes) == 0 {
+			break
+		}
+		expect(len(sliceNode.nodes)).ToBe(1)
+		node = sliceNode.nodes[0]
+		depth++
+	}
+	expect(depth).ToBe(maxTrieDepth - 1)
+	expect(bits.OnesCount32(node.(*SliceNode).dataMap)).ToBe(3)
+
+	for _, key := range []uint64{first, second, 0} {
+		expect(m.Contains(key)).ToBe(true)
+	}
+	m = m.remove(second).remove(uint64(0))
+	expect(m.root.(*SliceNode).nodeMap).ToBe(bitmapType(0))
+	expect(m.Contains(first)).ToBe(true)
+}
+
+func TestTrieIdenticalHashesCollide(t *testing.T) {
+	expect := expectFor(t)
+	constantHash := func(key interface{}, seed hashSeed) hashKeyType { return 1<<63 | 5 }
+	m := newHashMapWithHashFn(constantHash).set("a", 1).set("b", 2).set("c", 3)
+	root := m.root.(*SliceNode)
+	expect(root.nodeMap).ToBe(bitmapType(1 << 5))
+	collision := root.nodes[0].(*CollisionNode)
+	expect(len(collision.entries)).ToBe(3)
+	expect(m.Size()).ToBe(3)
+}
@@ -0,0 +1,252 @@
+package collections
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// Hashing for the keys of hash based collections.
+//
+// Any value that can be compared with == can be hashed. Values that
+// implement Hashable are hashed with their Hash method. Primitive
+// types take a fast path, and everything else (pointers, channels,
+// arrays, structs and interface values nested inside them) is hashed
+// by walking its structure with reflection. Slices, maps and
+// functions, or anything containing them, panic with ErrUnhashableType.
+//
+// Keys are compared with ==, and hashes agree with ==:
+//   - +0 and -0 are equal, so they hash the same.
+//   - NaN is not equal to anything, including itself. As with Go's
+//     built in maps, a NaN key can be set but never found, and each
+//     Set with a NaN key adds a new entry.
+//   - Values of different types are never equal, but may share a
+//     hash, e.g. int(1) and int64(1). They are kept apart by equality.
+
+// The hashing used by a hash based collection. Two tries can only be
+// combined structurally when their keys were placed by the same hashing,
+// see sameHashing. A nil hashFn means getHash.
+type keyHasher struct {
+	seed   hashSeed
+	hashFn func(key interface{}, seed hashSeed) hashKeyType
+}
+
+func (hasher *keyHasher) hash(key interface{}) hashKeyType {
+	if hasher.hashFn == nil {
+		return getHash(key, hasher.seed)
+	}
+	return hasher.hashFn(key, hasher.seed)
+}
+
+// Reports whether two hashers place every key in the same position
+func sameHashing(first *keyHasher, second *keyHasher) bool {
+	if first == second {
+		return true
+	}
+	return first.hashFn == nil && second.hashFn == nil && first.seed == second.seed
+}
+
+// A hashSeed is either a random maphash seed, which is fast but differs
+// between processes, or a fixed seed, which hashes every key the same way
+// in every process.
+type hashSeed struct {
+	random  maphash.Seed
+	fixed   uint64
+	isFixed bool
+}
+
+func randomHashSeed() hashSeed {
+	return hashSeed{random: maphash.MakeSeed()}
+}
+
+func fixedHashSeed(seed uint64) hashSeed {
+	return hashSeed{fixed: seed, isFixed: true}
+}
+
+// The running state of a hash. With a random seed it defers to maphash.
+// With a fixed seed it is FNV-1a, started from the seed and finished
+// with the MurmurHash3 finalizer, so that the low bits the trie indexes
+// on first depend on every byte written.
+type hashState struct {
+	random  maphash.Hash
+	fixed   uint64
+	isFixed bool
+}
+
+const (
+	fnvOffset64 uint64 = 14695981039346656037
+	fnvPrime64  uint64 = 1099511628211
+)
+
+func (h *hashState) reset(seed hashSeed) {
+	if seed.isFixed {
+		h.isFixed = true
+		h.fixed = fnvOffset64 ^ mixBits(seed.fixed)
+	} else {
+		h.random.SetSeed(seed.random)
+	}
+}
+
+func (h *hashState) writeByte(b byte) {
+	if h.isFixed {
+		h.fixed = (h.fixed ^ uint64(b)) * fnvPrime64
+	} else {
+		h.random.WriteByte(b)
+	}
+}
+
+func (h *hashState) write(b []byte) {
+	if h.isFixed {
+		for _, c := range b {
+			h.fixed = (h.fixed ^ uint64(c)) * fnvPrime64
+		}
+	} else {
+		h.random.Write(b)
+	}
+}
+
+func (h *hashState) writeString(s string) {
+	if h.isFixed {
+		for i := 0; i < len(s); i++ {
+			h.fixed = (h.fixed ^ uint64(s[i])) * fnvPrime64
+		}
+	} else {
+		h.random.WriteString(s)
+	}
+}
+
+func (h *hashState) sum64() uint64 {
+	if h.isFixed {
+		return mixBits(h.fixed)
+	}
+	return h.random.Sum64()
+}
+
+// The MurmurHash3 64 bit finalizer
+func mixBits(v uint64) uint64 {
+	v ^= v >> 33
+	v *= 0xff51afd7ed558ccd
+	v ^= v >> 33
+	v *= 0xc4ceb9fe1a85ec53
+	v ^= v >> 33
+	return v
+}
+
+// Tags written before values whose hashes would otherwise be
+// trivially related
+const (
+	nilHashTag byte = iota
+	falseHashTag
+	trueHashTag
+)
+
+func getHash(v interface{}, seed hashSeed) hashKeyType {
+	var h hashState
+	h.reset(seed)
+	switch v := v.(type) {
+	case Hashable:
+		writeUint64(&h, v.Hash())
+	case string:
+		h.writeString(v)
+	case int:
+		writeUint64(&h, uint64(v))
+	case int8:
+		writeUint64(&h, uint64(v))
+	case int16:
+		writeUint64(&h, uint64(v))
+	case int32:
+		writeUint64(&h, uint64(v))
+	case int64:
+		writeUint64(&h, uint64(v))
+	case uint:
+		writeUint64(&h, uint64(v))
+	case uint8:
+		writeUint64(&h, uint64(v))
+	case uint16:
+		writeUint64(&h, uint64(v))
+	case uint32:
+		writeUint64(&h, uint64(v))
+	case uint64:
+		writeUint64(&h, v)
+	case uintptr:
+		writeUint64(&h, uint64(v))
+	case bool:
+		writeBool(&h, v)
+	case float32:
+		writeFloat(&h, float64(v))
+	case float64:
+		writeFloat(&h, v)
+	case complex64:
+		writeFloat(&h, float64(real(v)))
+		writeFloat(&h, float64(imag(v)))
+	case complex128:
+		writeFloat(&h, real(v))
+		writeFloat(&h, imag(v))
+	case nil:
+		h.writeByte(nilHashTag)
+	default:
+		writeValue(&h, reflect.ValueOf(v))
+	}
+
+	return h.sum64()
+}
+
+// Hashes a value of any comparable type by walking its structure
+func writeValue(h *hashState, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		h.writeString(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint64(h, v.Uint())
+	case reflect.Bool:
+		writeBool(h, v.Bool())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		writeFloat(h, real(v.Complex()))
+		writeFloat(h, imag(v.Complex()))
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		writeUint64(h, uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			h.writeByte(nilHashTag)
+		} else {
+			writeValue(h, v.Elem())
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			writeValue(h, v.Field(i))
+		}
+	default:
+		panic(ErrUnhashableType)
+	}
+}
+
+func writeUint64(h *hashState, v uint64) {
+	var buffer [8]byte
+	binary.LittleEndian.PutUint64(buffer[:], v)
+	h.write(buffer[:])
+}
+
+func writeBool(h *hashState, v bool) {
+	if v {
+		h.writeByte(trueHashTag)
+	} else {
+		h.writeByte(falseHashTag)
+	}
+}
+
+func writeFloat(h *hashState, v float64) {
+	// -0 == +0, so both must hash as +0
+	if v == 0 {
+		v = 0
+	}
+	writeUint64(h, math.Float64bits(v))
+}
@@ -0,0 +1,166 @@
+package collections
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+type hashTestPoint struct {
+	x, y int
+	name string
+}
+
+type hashTestNested struct {
+	point hashTestPoint
+	tags  [2]string
+	extra interface{}
+}
+
+func TestHashPrimitives(t *testing.T) {
+	expect := expectFor(t)
+	seed := randomHashSeed()
+	values := []interface{}{
+		42, int8(42), int16(42), int32(42), int64(42),
+		uint(42), uint8(42), uint16(42), uint32(42), uint64(42), uintptr(42),
+		true, 'x', byte('x'), 4.2, float32(4.2), complex(1, 2), complex64(complex(1, 2)),
+		"forty two", nil,
+	}
+	for _, value := range values {
+		expect(func() { getHash(value, seed) }).Not().ToPanicWith(ErrUnhashableType)
+		expect(getHash(value, seed)).ToBe(getHash(value, seed))
+	}
+	expect(getHash(true, seed)).Not().ToBe(getHash(false, seed))
+	expect(getHash(1, seed)).Not().ToBe(getHash(2, seed))
+}
+
+func TestHashFloatZeroes(t *testing.T) {
+	expect := expectFor(t)
+	seed := randomHashSeed()
+	negativeZero := math.Copysign(0, -1)
+	expect(getHash(negativeZero, seed)).ToBe(getHash(0.0, seed))
+	expect(getHash(float32(negativeZero), seed)).ToBe(getHash(float32(0), seed))
+	expect(getHash(complex(negativeZero, negativeZero), seed)).ToBe(getHash(complex(0, 0), seed))
+	expect(getHash([1]float64{negativeZero}, seed)).ToBe(getHash([1]float64{0}, seed))
+}
+
+func TestHashComposites(t *testing.T) {
+	expect := expectFor(t)
+	seed := randomHashSeed()
+	a := hashTestNested{hashTestPoint{1, 2, "a"}, [2]string{"x", "y"}, 3}
+	b := hashTestNested{hashTestPoint{1, 2, "a"}, [2]string{"x", "y"}, 3}
+	c := hashTestNested{hashTestPoint{1, 2, "a"}, [2]string{"x", "z"}, 3}
+	expect(getHash(a, seed)).ToBe(getHash(b, seed))
+	expect(getHash(a, seed)).Not().ToBe(getHash(c, seed))
+	expect(getHash([3]int{1, 2, 3}, seed)).ToBe(getHash([3]int{1, 2, 3}, seed))
+
+	point := &hashTestPoint{1, 2, "a"}
+	otherPoint := &hashTestPoint{1, 2, "a"}
+	expect(getHash(point, seed)).ToBe(getHash(point, seed))
+	expect(getHash(point, seed)).Not().ToBe(getHash(otherPoint, seed))
+
+	channel := make(chan int)
+	expect(getHash(channel, seed)).ToBe(getHash(channel, seed))
+}
+
+func TestHashUnhashableTypes(t *testing.T) {
+	expect := expectFor(t)
+	seed := randomHashSeed()
+	expect(func() { getHash([]int{1}, seed) }).ToPanicWith(ErrUnhashableType)
+	expect(func() { getHash(map[string]int{}, seed) }).ToPanicWith(ErrUnhashableType)
+	expect(func() { getHash(func() {}, seed) }).ToPanicWith(ErrUnhashableType)
+	expect(func() { getHash(hashTestNested{extra: []int{1}}, seed) }).ToPanicWith(ErrUnhashableType)
+}
+
+func TestHashMapPrimitiveKeys(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().Set(42, "int").Set(int64(42), "int64").Set(true, "bool").Set('x', "rune").Set(byte('x'), "byte")
+	expect(m.(*HashMap).Size()).ToBe(5)
+
+	val, _ := m.Get(42)
+	expect(val).ToBe("int")
+	val, _ = m.Get(int64(42))
+	expect(val).ToBe("int64")
+	val, _ = m.Get('x')
+	expect(val).ToBe("rune")
+	val, _ = m.Get(byte('x'))
+	expect(val).ToBe("byte")
+}
+
+func TestHashMapFloatKeys(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().Set(0.0, "zero").Set(math.Copysign(0, -1), "negative zero")
+	expect(m.(*HashMap).Size()).ToBe(1)
+	val, _ := m.Get(0.0)
+	expect(val).ToBe("negative zero")
+
+	nan := math.NaN()
+	withNaN := m.Set(nan, 1).Set(nan, 2)
+	expect(withNaN.(*HashMap).Size()).ToBe(3)
+	expect(withNaN.Contains(nan)).ToBe(false)
+}
+
+func TestHashMapStructKeys(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().Set(hashTestPoint{1, 2, "a"}, 1).Set([2]int{1, 2}, 2)
+	val, found := m.Get(hashTestPoint{1, 2, "a"})
+	expect(val).ToBe(1)
+	expect(found).ToBe(true)
+	val, found = m.Get([2]int{1, 2})
+	expect(val).ToBe(2)
+	expect(found).ToBe(true)
+	expect(m.Contains(hashTestPoint{1, 2, "b"})).ToBe(false)
+}
+
+func TestFixedSeedHashesAreStable(t *testing.T) {
+	expect := expectFor(t)
+	// These must not change between runs, or between releases
+	expect(getHash("hello", fixedHashSeed(42))).ToBe(hashKeyType(3606887481151175172))
+	expect(getHash(42, fixedHashSeed(42))).ToBe(hashKeyType(12286534953798567217))
+	expect(getHash("hello", fixedHashSeed(7))).ToBe(hashKeyType(5972202047851122628))
+}
+
+func TestSameHashing(t *testing.T) {
+	expect := expectFor(t)
+	hashMap := NewHashMap()
+	expect(sameHashing(hashMap.hasher, hashMap.set(1, 1).hasher)).ToBe(true)
+	expect(sameHashing(hashMap.hasher, NewHashMap().hasher)).ToBe(false)
+	expect(sameHashing(NewHashMapWithSeed(1).hasher, NewHashMapWithSeed(1).hasher)).ToBe(true)
+	expect(sameHashing(NewHashMapWithSeed(1).hasher, NewHashMapWithSeed(2).hasher)).ToBe(false)
+	expect(sameHashing(NewHashMapWithSeed(1).hasher, hashMap.hasher)).ToBe(false)
+	expect(sameHashing(newHashMapWithHashFn(identityHash).hasher, newHashMapWithHashFn(identityHash).hasher)).ToBe(false)
+}
+
+func TestHashMapWithSeedIteratesDeterministically(t *testing.T) {
+	expect := expectFor(t)
+	build := func(seed uint64) Map {
+		var m Map = NewHashMapWithSeed(seed)
+		for i := 0; i < 500; i++ {
+			m = m.Set(fmt.Sprintf("key-%d", i), i)
+		}
+		return m
+	}
+	expect(build(42).ToSlice()).ToDeepEqual(build(42).ToSlice())
+	expect(build(42).ToSlice()).Not().ToDeepEqual(build(43).ToSlice())
+	expect(NewHashSetWithSeed(9, "a", "b", "c", "d").ToSlice()).ToDeepEqual(NewHashSetWithSeed(9, "d", "c", "b", "a").ToSlice())
+}
+
+func TestHashMapsWithDifferentSeedsCombine(t *testing.T) {
+	expect := expectFor(t)
+	left := NewHashMapWithSeed(1).Set("a", 1).Set("b", 2)
+	right := NewHashMapWithSeed(2).Set("b", 20).Set("c", 30)
+	merged := left.Merge(right)
+	expect(merged.(*HashMap).Size()).ToBe(3)
+	val, _ := merged.Get("b")
+	expect(val).ToBe(20)
+	merged = NewHashMap().Merge(right)
+	val, _ = merged.Get("c")
+	expect(val).ToBe(30)
+
+	first := NewHashSetWithSeed(1, 1, 2, 3)
+	second := NewHashSetWithSeed(2, 2, 3, 4)
+	expect(first.Union(second).Size()).ToBe(4)
+	expect(first.Intersect(second).Size()).ToBe(2)
+	expect(first.Difference(second).Size()).ToBe(1)
+	expect(first.SubsetOf(NewHashSet(1, 2, 3, 4))).ToBe(true)
+}
@@ -0,0 +1,217 @@
+package collections
+
+// A HashSet is an immutable Set backed by the same hash array
+// mapped trie as HashMap. The elements of the set are the keys of
+// the underlying map. HashSets do not guarentee iteration order.
+//
+// When both operands are HashSets (or key sets of HashMaps) using the
+// same hashing, SubsetOf, Intersect, Union and Difference walk both
+// tries together and reuse whole sub-trees, rather than working an
+// element at a time. Against any other Set they fall back to Contains.
+type HashSet struct {
+	hashMap *HashMap
+}
+
+var _ Set = (*HashSet)(nil)
+
+// Factory for HashSets
+func NewHashSet(values ...interface{}) *HashSet {
+	return newHashSetWithMap(NewHashMap(), values)
+}
+
+// Returns a HashSet of the values whose elements are hashed with the
+// given seed, so that it iterates in the same order in every run. See
+// NewHashMapWithSeed.
+func NewHashSetWithSeed(seed uint64, values ...interface{}) *HashSet {
+	return newHashSetWithMap(NewHashMapWithSeed(seed), values)
+}
+
+func newHashSetWithMap(hashMap *HashMap, values []interface{}) *HashSet {
+	for _, value := range values {
+		hashMap = hashMap.set(value, nil)
+	}
+	return &HashSet{
+		hashMap: hashMap,
+	}
+}
+
+// Set Methods
+
+func (set *HashSet) Size() int {
+	return set.hashMap.size
+}
+
+func (set *HashSet) Contains(value interface{}) bool {
+	return set.hashMap.Contains(value)
+}
+
+func (set *HashSet) SubsetOf(other Set) bool {
+	if otherSet, ok := set.sameLayoutAs(other); ok {
+		return subsetNodes(set.hashMap.root, otherSet.hashMap.root, 0)
+	}
+	if set.Size() > other.Size() {
+		return false
+	}
+	return !set.Any(func(value interface{}) bool {
+		return !other.Contains(value)
+	})
+}
+
+func (set *HashSet) Add(value interface{}) Set {
+	if set.Contains(value) {
+		return set
+	}
+	return set.withMap(set.hashMap.set(value, nil))
+}
+
+func (set *HashSet) Remove(value interface{}) Set {
+	return set.withMap(set.hashMap.remove(value))
+}
+
+func (set *HashSet) Intersect(other Set) Set {
+	if otherSet, ok := set.sameLayoutAs(other); ok {
+		return set.withTrie(intersectNodes(set.hashMap.root, otherSet.hashMap.root, 0))
+	}
+	result := set.hashMap
+	set.ForEach(func(value interface{}) {
+		if !other.Contains(value) {
+			result = result.remove(value)
+		}
+	})
+	return set.withMap(result)
+}
+
+func (set *HashSet) Union(other Set) Set {
+	if otherSet, ok := other.(*HashSet); ok && set.Size() == 0 {
+		return otherSet
+	}
+	if otherSet, ok := set.sameLayoutAs(other); ok {
+		return set.withTrie(unionNodes(set.hashMap.root, otherSet.hashMap.root, 0, nil))
+	}
+	result := set.hashMap
+	other.ForEach(func(value interface{}) {
+		if !result.Contains(value) {
+			result = result.set(value, nil)
+		}
+	})
+	return set.withMap(result)
+}
+
+func (set *HashSet) Difference(other Set) Set {
+	if otherSet, ok := set.sameLayoutAs(other); ok {
+		return set.withTrie(differenceNodes(set.hashMap.root, otherSet.hashMap.root, 0))
+	}
+	result := set.hashMap
+	other.ForEach(func(value interface{}) {
+		result = result.remove(value)
+	})
+	return set.withMap(result)
+}
+
+// Returns other as a HashSet if its trie lines up with this one,
+// so the two can be combined structurally
+func (set *HashSet) sameLayoutAs(other Set) (*HashSet, bool) {
+	otherSet, ok := other.(*HashSet)
+	if !ok || !sameHashing(set.hashMap.hasher, otherSet.hashMap.hasher) {
+		return nil, false
+	}
+	return otherSet, true
+}
+
+func (set *HashSet) withTrie(root HAMTNode) *HashSet {
+	return set.withMap(set.hashMap.withTrie(root))
+}
+
+func (set *HashSet) withMap(hashMap *HashMap) *HashSet {
+	if hashMap == set.hashMap {
+		return set
+	}
+	return &HashSet{
+		hashMap: hashMap,
+	}
+}
+
+// Iterable Methods
+
+func (set *HashSet) Iterator() Iterator {
+	return &HashSetIterator{
+		trie: newTrieIterator(set.hashMap.root),
+	}
+}
+
+func (set *HashSet) ForEach(iterFn func(interface{})) {
+	forEachHelper(set, iterFn)
+}
+
+func (set *HashSet) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(set, mapFn)
+}
+
+func (set *HashSet) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(set, filterFn)
+}
+
+func (set *HashSet) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(set, initialValue, reducerFn)
+}
+
+func (set *HashSet) ToSlice() []interface{} {
+	return toSliceHelper(set)
+}
+
+func (set *HashSet) ToVector() *Vector {
+	return toVectorHelper(set)
+}
+
+func (set *HashSet) Take(count int) Iterable {
+	return takeHelper(set, count)
+}
+
+func (set *HashSet) Skip(count int) Iterable {
+	return skipHelper(set, count)
+}
+
+func (set *HashSet) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(set, matchFn)
+}
+
+func (set *HashSet) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(set, groupFn)
+}
+
+func (set *HashSet) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(set, groupFn, initialValue, reducerFn)
+}
+
+func (set *HashSet) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(set, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Set with the same elements
+func (set *HashSet) Equals(other interface{}) bool {
+	if otherSet, ok := other.(Set); ok {
+		if otherHashSet, ok := set.sameLayoutAs(otherSet); ok {
+			return set.Size() == otherHashSet.Size() && subsetNodes(set.hashMap.root, otherHashSet.hashMap.root, 0)
+		}
+	}
+	return equalSets(set, other)
+}
+
+func (set *HashSet) Hash() uint64 {
+	return hashOfSet(set)
+}
+
+// An Iterator over the elements of a HashSet
+type HashSetIterator struct {
+	trie *trieIterator
+}
+
+func (iterator *HashSetIterator) MoveNext() bool {
+	return iterator.trie.MoveNext()
+}
+
+func (iterator *HashSetIterator) Current() interface{} {
+	return iterator.trie.current().key
+}
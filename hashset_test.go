@@ -0,0 +1,222 @@
+package collections
+
+import (
+	"testing"
+)
+
+func TestHashSetAddAndRemove(t *testing.T) {
+	expect := expectFor(t)
+	s0 := NewHashSet()
+	s1 := s0.Add(int64(1)).Add(int64(2)).Add(int64(2))
+	s2 := s1.Remove(int64(1))
+
+	expect(s0.Size()).ToBe(0)
+	expect(s1.Size()).ToBe(2)
+	expect(s2.Size()).ToBe(1)
+	expect(s1.Contains(int64(1))).ToBe(true)
+	expect(s2.Contains(int64(1))).ToBe(false)
+	expect(s2.Contains(int64(2))).ToBe(true)
+}
+
+func TestHashSetOperations(t *testing.T) {
+	expect := expectFor(t)
+	a := NewHashSet("a", "b", "c")
+	b := NewHashSet("b", "c", "d")
+
+	expect(a.Union(b).Size()).ToBe(4)
+	expect(a.Intersect(b).Size()).ToBe(2)
+	expect(a.Intersect(b).Contains("a")).ToBe(false)
+	expect(a.Difference(b).Size()).ToBe(1)
+	expect(a.Difference(b).Contains("a")).ToBe(true)
+	expect(a.SubsetOf(b)).ToBe(false)
+	expect(a.Intersect(b).SubsetOf(a)).ToBe(true)
+	expect(a.Intersect(b).SubsetOf(b)).ToBe(true)
+}
+
+func TestHashSetHashableElements(t *testing.T) {
+	expect := expectFor(t)
+	set := NewHashSet(compositeID{"acme", []int64{1}}, compositeID{"acme", []int64{1}}, compositeID{"acme", []int64{2}})
+	expect(set.Size()).ToBe(2)
+	expect(set.Contains(compositeID{"acme", []int64{2}})).ToBe(true)
+	expect(set.Remove(compositeID{"acme", []int64{2}}).Size()).ToBe(1)
+}
+
+// A deliberately weak hash for int elements, so that
+// elements collide often
+func moduloSixteenHash(key interface{}, seed hashSeed) hashKeyType {
+	return getHash(key.(int)%16, seed)
+}
+
+func hashSetWithHasher(hasher *keyHasher, values ...interface{}) *HashSet {
+	hashMap := newHashMapWithHasher(hasher)
+	for _, value := range values {
+		hashMap = hashMap.set(value, nil)
+	}
+	return &HashSet{hashMap: hashMap}
+}
+
+func randomIntSet(random *faker, hasher *keyHasher, maxSize int) *HashSet {
+	values := []interface{}{}
+	for i := random.rand.Intn(maxSize); i > 0; i-- {
+		values = append(values, random.rand.Intn(300))
+	}
+	return hashSetWithHasher(hasher, values...)
+}
+
+func setsEqual(first Set, second Set) bool {
+	return first.Size() == second.Size() && first.SubsetOf(second) && second.SubsetOf(first)
+}
+
+// Checks the set against a slow but obviously correct model
+func expectSetMatches(t *testing.T, set Set, model func(value int) bool) {
+	t.Helper()
+	expected := 0
+	for i := 0; i < 300; i++ {
+		if model(i) {
+			expected++
+			if !set.Contains(i) {
+				t.Fatalf("expected set to contain %d", i)
+			}
+		} else if set.Contains(i) {
+			t.Fatalf("expected set not to contain %d", i)
+		}
+	}
+	if set.Size() != expected || len(set.ToSlice()) != expected {
+		t.Fatalf("expected set of size %d, got %d", expected, set.Size())
+	}
+	if hashSet, ok := set.(*HashSet); ok {
+		expectCompactTrie(t, hashSet.hashMap.root, 0)
+	}
+}
+
+func hashersUnderTest() map[string][2]*keyHasher {
+	shared := &keyHasher{seed: randomHashSeed()}
+	weak := &keyHasher{seed: randomHashSeed(), hashFn: moduloSixteenHash}
+	return map[string][2]*keyHasher{
+		"shared":      {shared, shared},
+		"collisions":  {weak, weak},
+		"mismatched":  {shared, weak},
+		"other seeds": {{seed: randomHashSeed()}, {seed: randomHashSeed()}},
+	}
+}
+
+func TestHashSetAlgebraMatchesModel(t *testing.T) {
+	random := fakerFor(t)
+	for name, hashers := range hashersUnderTest() {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				a := randomIntSet(random, hashers[0], 200)
+				b := randomIntSet(random, hashers[1], 200)
+				expectSetMatches(t, a.Union(b), func(v int) bool { return a.Contains(v) || b.Contains(v) })
+				expectSetMatches(t, a.Intersect(b), func(v int) bool { return a.Contains(v) && b.Contains(v) })
+				expectSetMatches(t, a.Difference(b), func(v int) bool { return a.Contains(v) && !b.Contains(v) })
+
+				subset := true
+				for v := 0; v < 300; v++ {
+					subset = subset && (!a.Contains(v) || b.Contains(v))
+				}
+				if a.SubsetOf(b) != subset {
+					t.Fatalf("expected SubsetOf to be %v", subset)
+				}
+			}
+		})
+	}
+}
+
+func TestHashSetAlgebraLaws(t *testing.T) {
+	random := fakerFor(t)
+	for name, hashers := range hashersUnderTest() {
+		t.Run(name, func(t *testing.T) {
+			expect := expectFor(t)
+			for i := 0; i < 100; i++ {
+				a := randomIntSet(random, hashers[0], 150)
+				b := randomIntSet(random, hashers[1], 150)
+				c := randomIntSet(random, hashers[0], 150)
+				empty := hashSetWithHasher(hashers[1])
+
+				// Identity and annihilation
+				expect(setsEqual(a.Union(empty), a)).ToBe(true)
+				expect(a.Intersect(empty).Size()).ToBe(0)
+				expect(setsEqual(a.Difference(empty), a)).ToBe(true)
+				expect(empty.Difference(a).Size()).ToBe(0)
+
+				// Idempotence
+				expect(setsEqual(a.Union(a), a)).ToBe(true)
+				expect(setsEqual(a.Intersect(a), a)).ToBe(true)
+				expect(a.Difference(a).Size()).ToBe(0)
+
+				// Commutativity
+				expect(setsEqual(a.Union(b), b.Union(a))).ToBe(true)
+				expect(setsEqual(a.Intersect(b), b.Intersect(a))).ToBe(true)
+
+				// Associativity
+				expect(setsEqual(a.Union(b).Union(c), a.Union(b.Union(c)))).ToBe(true)
+				expect(setsEqual(a.Intersect(b).Intersect(c), a.Intersect(b.Intersect(c)))).ToBe(true)
+
+				// Absorption
+				expect(setsEqual(a.Union(a.Intersect(b)), a)).ToBe(true)
+				expect(setsEqual(a.Intersect(a.Union(b)), a)).ToBe(true)
+
+				// Distributivity
+				expect(setsEqual(a.Intersect(b.Union(c)), a.Intersect(b).Union(a.Intersect(c)))).ToBe(true)
+				expect(setsEqual(a.Union(b.Intersect(c)), a.Union(b).Intersect(a.Union(c)))).ToBe(true)
+
+				// Difference
+				expect(setsEqual(a.Difference(b.Union(c)), a.Difference(b).Intersect(a.Difference(c)))).ToBe(true)
+				expect(setsEqual(a.Difference(b.Intersect(c)), a.Difference(b).Union(a.Difference(c)))).ToBe(true)
+				expect(a.Difference(b).Intersect(b).Size()).ToBe(0)
+				expect(setsEqual(a.Difference(b).Union(a.Intersect(b)), a)).ToBe(true)
+
+				// Subsets
+				expect(a.SubsetOf(a)).ToBe(true)
+				expect(empty.SubsetOf(a)).ToBe(true)
+				expect(a.Intersect(b).SubsetOf(a)).ToBe(true)
+				expect(a.SubsetOf(a.Union(b))).ToBe(true)
+				expect(a.Difference(b).SubsetOf(a)).ToBe(true)
+				expect(a.SubsetOf(b) && b.SubsetOf(a)).ToBe(setsEqual(a, b))
+			}
+		})
+	}
+}
+
+func TestHashSetAlgebraSharesStructure(t *testing.T) {
+	expect := expectFor(t)
+	a := NewHashSet()
+	for i := 0; i < 1000; i++ {
+		a = a.Add(i).(*HashSet)
+	}
+	b := a.Add(5000).(*HashSet)
+
+	expect(a.Union(a)).ToBe(a)
+	expect(a.Intersect(a)).ToBe(a)
+	expect(a.Union(NewHashSet())).ToBe(a)
+	expect(NewHashSet().Union(a)).ToBe(a)
+	expect(b.Union(a)).ToBe(b)
+	expect(a.Intersect(b)).ToBe(a)
+	expect(a.Difference(NewHashSet())).ToBe(a)
+
+	// Only the branch holding 5000 differs, so every other
+	// branch of the union is shared with a
+	union := a.Union(b).(*HashSet)
+	unionRoot := union.hashMap.root.(*SliceNode)
+	aRoot := a.hashMap.root.(*SliceNode)
+	shared := 0
+	for i := range unionRoot.nodes {
+		for j := range aRoot.nodes {
+			if unionRoot.nodes[i] == aRoot.nodes[j] {
+				shared++
+			}
+		}
+	}
+	expect(shared >= len(aRoot.nodes)-1).ToBe(true)
+}
+
+func TestHashSetAlgebraWithOtherSets(t *testing.T) {
+	expect := expectFor(t)
+	keySet := NewHashMap().Set(1, "one").Set(2, "two").KeySet()
+	set := NewHashSet(2, 3)
+	expect(set.Union(keySet).Size()).ToBe(3)
+	expect(set.Intersect(keySet).Size()).ToBe(1)
+	expect(set.Difference(keySet).Contains(3)).ToBe(true)
+	expect(set.Difference(keySet).Size()).ToBe(1)
+}
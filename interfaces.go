@@ -33,6 +33,10 @@ type Iterable interface {
 	// Note that if the iterable is infinite, this will loop forever.
 	ToSlice() []interface{}
 
+	// Returns a vector whose elements are the items of the iterable.
+	// If the iterable is infinite, loops infinitely
+	ToVector() *Vector
+
 	// Returns an Iterable with the first "count" items of the Iterable
 	// If the iterable has fewer than "count" items, returns an iterable
 	// with all of them.
@@ -46,7 +50,18 @@ type Iterable interface {
 	// Iterable with the remaining items.
 	SkipWhile(matchFn func(interface{}) bool) Iterable
 
-	// GroupBy(groupFn func(interface{}) interface{}) Map
+	// Returns a Map from each key returned by groupFn to a Sequence
+	// of the items for which groupFn returned that key, in the order
+	// they were iterated. Note that if the iterable is infinite,
+	// this will loop forever.
+	GroupBy(groupFn func(interface{}) interface{}) Map
+
+	// Returns a Map from each key returned by groupFn to the result
+	// of folding the items for which groupFn returned that key, as Fold
+	// does, starting from initialValue. Unlike GroupBy, the groups
+	// themselves are never built. Note that if the iterable is infinite,
+	// this will loop forever.
+	GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map
 
 	// Returns true if any items in the iterable. Note that
 	// if the iterable is infinite and matchFn is false for all items
@@ -74,10 +89,6 @@ type Iterable interface {
 	// // Guarenteed to return the same results as Any(func(interface{}) bool {return true})
 	// IsEmpty() bool
 
-	// // Returns a vector whose elements are the items of the iterable.
-	// // If the iterable is infinite, loops infinitely
-	// ToVector() Vector
-
 	// // Returns a Map whose keys are values returned by keyFn, and whose
 	// // values are the values returned by valueFn. If multiple items return
 	// // the same key, the last one will be used.
@@ -130,6 +141,10 @@ type Sequence interface {
 	// All Sequences are also FiniteIterables
 	FiniteIterable
 
+	// Sequences are equal when they have equal elements in the
+	// same order, even if they are different types of Sequence
+	Hashable
+
 	// Gets the element at the specified index. Panics with
 	// ErrIndexOutOfRange if the index is out of range.
 	Get(index int) interface{}
@@ -145,13 +160,15 @@ type Sequence interface {
 	// end of the collection
 	Append(value interface{}) Sequence
 
-	// // Functional Prepend.
-	// // Creates a copy of the sequence with a new value at the
-	// // start of the collection
-	// Prepend(value interface{}) Sequence
+	// Functional Prepend.
+	// Creates a copy of the sequence with a new value at the
+	// start of the collection
+	Prepend(value interface{}) Sequence
 
-	// // Slice. Creates a new sequence
-	// Slice(start int, end int) Sequence
+	// Slice. Creates a new sequence with the elements from start
+	// up to but not including end. Panics with ErrIndexOutOfRange
+	// unless 0 <= start <= end <= Size().
+	Slice(start int, end int) Sequence
 }
 
 // A Set is an immutable iterable with no duplicates. Sets support standard
@@ -160,6 +177,10 @@ type Sequence interface {
 type Set interface {
 	FiniteIterable
 
+	// Sets are equal when they have the same elements, even if
+	// they are different types of Set
+	Hashable
+
 	Contains(value interface{}) bool
 	SubsetOf(other Set) bool
 	Add(value interface{}) Set
@@ -173,13 +194,37 @@ type Set interface {
 // Key-Value store semantics. Maps do not in general guarentee
 // iteration order, though many implementations do
 type Map interface {
-	Iterable
+	FiniteIterable
+
+	// Maps are equal when they have the same keys with equal
+	// values, even if they are different types of Map
+	Hashable
 	Contains(key interface{}) bool
 	Get(key interface{}) (interface{}, bool)
 	Set(key interface{}, value interface{}) Map
 	Remove(key interface{}) Map
+	// Returns a map with the entries of both maps. Where both
+	// maps contain a key, the value from other wins.
 	Merge(other Map) Map
+	// Returns a map with the entries of both maps. Where both
+	// maps contain a key, the value is resolve(key, left, right),
+	// where left is the value in this map and right the value in other.
+	MergeWith(other Map, resolve func(key interface{}, left interface{}, right interface{}) interface{}) Map
 	Keys() Iterable
 	Values() Iterable
 	KeySet() Set
 }
+
+// A Hashable is a value that supplies its own hashing and equality.
+// Hashables can be used as keys of a HashMap or elements of a HashSet
+// even when they could not be hashed or compared with == otherwise.
+//
+// Implementations must ensure that values which are Equal
+// return the same Hash.
+type Hashable interface {
+	// Returns a hash of the value
+	Hash() uint64
+
+	// Returns true if other is equal to the value
+	Equals(other interface{}) bool
+}
@@ -46,6 +46,10 @@ func toSliceHelper(iterable Iterable) []interface{} {
 	return slice
 }
 
+func toVectorHelper(iterable Iterable) *Vector {
+	return vectorFromSlice(toSliceHelper(iterable))
+}
+
 func takeHelper(interable Iterable, count int) Iterable {
 	if count < 0 {
 		panic(ErrInvalidTakeArgument)
@@ -80,6 +84,40 @@ func skipWhileHelper(iterable Iterable, matchFn func(interface{}) bool) Iterable
 	return NewStream(iterator)
 }
 
+func groupByHelper(iterable Iterable, groupFn func(interface{}) interface{}) Map {
+	groups := NewHashMap().Transient()
+	iterable.ForEach(func(item interface{}) {
+		key := groupFn(item)
+		group, found := groups.Get(key)
+		if !found {
+			group = &[]interface{}{}
+			groups.Set(key, group)
+		}
+		items := group.(*[]interface{})
+		*items = append(*items, item)
+	})
+	itemsByKey := groups.Persistent()
+	result := itemsByKey.Transient()
+	itemsByKey.ForEach(func(item interface{}) {
+		entry := item.(MapEntry)
+		result.Set(entry.Key, NewSliceSequence(*entry.Value.(*[]interface{})...))
+	})
+	return result.Persistent()
+}
+
+func groupByAggregateHelper(iterable Iterable, groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	groups := NewHashMap().Transient()
+	iterable.ForEach(func(item interface{}) {
+		key := groupFn(item)
+		state, found := groups.Get(key)
+		if !found {
+			state = initialValue
+		}
+		groups.Set(key, reducerFn(state, item))
+	})
+	return groups.Persistent()
+}
+
 func anyHelper(iterable Iterable, matchFn func(interface{}) bool) bool {
 	iterator := iterable.Iterator()
 	for iterator.MoveNext() {
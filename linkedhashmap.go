@@ -0,0 +1,237 @@
+package collections
+
+// A LinkedHashMap is an immutable Map that iterates over its entries
+// in the order their keys were first set. Setting a key that is
+// already in the map changes its value but keeps its position.
+//
+// A LinkedHashMap pairs a HashMap from each key to its value and
+// position with a SortedMap from each position to its key. Positions
+// are handed out in increasing order, so iterating over the SortedMap
+// visits keys in insertion order. Removing a key removes its position
+// too, so positions never need renumbering, and every update is
+// O(log n) in the worst case, whichever versions it is applied to.
+type LinkedHashMap struct {
+	entries *HashMap
+	order   *SortedMap
+	next    int
+}
+
+// The value stored for each key in LinkedHashMap.entries
+type linkedEntry struct {
+	position int
+	value    interface{}
+}
+
+var _ Map = (*LinkedHashMap)(nil)
+
+// Factory for LinkedHashMaps
+func NewLinkedHashMap() *LinkedHashMap {
+	return &LinkedHashMap{
+		entries: NewHashMap(),
+		order:   NewSortedMap(comparePositions),
+		next:    0,
+	}
+}
+
+func comparePositions(a interface{}, b interface{}) int {
+	return a.(int) - b.(int)
+}
+
+// Map Methods
+
+// The number of entries in the map
+func (linkedMap *LinkedHashMap) Size() int {
+	return linkedMap.entries.size
+}
+
+func (linkedMap *LinkedHashMap) Contains(key interface{}) bool {
+	return linkedMap.entries.Contains(key)
+}
+
+func (linkedMap *LinkedHashMap) Get(key interface{}) (interface{}, bool) {
+	entry, found := linkedMap.entries.Get(key)
+	if !found {
+		return nil, false
+	}
+	return entry.(linkedEntry).value, true
+}
+
+// Sets the value for the key. A new key goes after every other key,
+// while an existing key keeps its position.
+func (linkedMap *LinkedHashMap) Set(key interface{}, value interface{}) Map {
+	return linkedMap.set(key, value)
+}
+
+func (linkedMap *LinkedHashMap) set(key interface{}, value interface{}) *LinkedHashMap {
+	if existing, found := linkedMap.entries.Get(key); found {
+		entry := existing.(linkedEntry)
+		if sameValue(entry.value, value) {
+			return linkedMap
+		}
+		return &LinkedHashMap{
+			entries: linkedMap.entries.set(key, linkedEntry{position: entry.position, value: value}),
+			order:   linkedMap.order,
+			next:    linkedMap.next,
+		}
+	}
+	return &LinkedHashMap{
+		entries: linkedMap.entries.set(key, linkedEntry{position: linkedMap.next, value: value}),
+		order:   linkedMap.order.set(linkedMap.next, key),
+		next:    linkedMap.next + 1,
+	}
+}
+
+func (linkedMap *LinkedHashMap) Remove(key interface{}) Map {
+	existing, found := linkedMap.entries.Get(key)
+	if !found {
+		return linkedMap
+	}
+	return &LinkedHashMap{
+		entries: linkedMap.entries.remove(key),
+		order:   linkedMap.order.remove(existing.(linkedEntry).position),
+		next:    linkedMap.next,
+	}
+}
+
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value from other wins. Keys only in other
+// are added after the keys of this map, in the order of other.
+func (linkedMap *LinkedHashMap) Merge(other Map) Map {
+	return linkedMap.MergeWith(other, func(key interface{}, left interface{}, right interface{}) interface{} {
+		return right
+	})
+}
+
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value is the result of calling resolve with
+// the key, the value in this map and the value in other. Keys only
+// in other are added after the keys of this map, in the order of other.
+func (linkedMap *LinkedHashMap) MergeWith(other Map, resolve func(key interface{}, left interface{}, right interface{}) interface{}) Map {
+	return other.Fold(linkedMap, func(state interface{}, item interface{}) interface{} {
+		result, entry := state.(*LinkedHashMap), item.(MapEntry)
+		value := entry.Value
+		if existing, found := result.Get(entry.Key); found {
+			value = resolve(entry.Key, existing, entry.Value)
+		}
+		return result.set(entry.Key, value)
+	}).(*LinkedHashMap)
+}
+
+// Returns a lazy Iterable over the keys of the map, in insertion order
+func (linkedMap *LinkedHashMap) Keys() Iterable {
+	return linkedMap.Map(func(entry interface{}) interface{} {
+		return entry.(MapEntry).Key
+	})
+}
+
+// Returns a lazy Iterable over the values of the map, in insertion order
+func (linkedMap *LinkedHashMap) Values() Iterable {
+	return linkedMap.Map(func(entry interface{}) interface{} {
+		return entry.(MapEntry).Value
+	})
+}
+
+// Returns the keys of the map as a Set in O(1). Note that the
+// set does not keep the insertion order of the map.
+func (linkedMap *LinkedHashMap) KeySet() Set {
+	return linkedMap.entries.KeySet()
+}
+
+// Iterable Methods
+
+func (linkedMap *LinkedHashMap) Iterator() Iterator {
+	return &LinkedHashMapIterator{
+		linkedMap: linkedMap,
+		order:     linkedMap.order.Iterator(),
+	}
+}
+
+func (linkedMap *LinkedHashMap) ForEach(iterFn func(interface{})) {
+	forEachHelper(linkedMap, iterFn)
+}
+
+func (linkedMap *LinkedHashMap) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(linkedMap, mapFn)
+}
+
+func (linkedMap *LinkedHashMap) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(linkedMap, filterFn)
+}
+
+func (linkedMap *LinkedHashMap) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(linkedMap, initialValue, reducerFn)
+}
+
+func (linkedMap *LinkedHashMap) ToSlice() []interface{} {
+	return toSliceHelper(linkedMap)
+}
+
+func (linkedMap *LinkedHashMap) ToVector() *Vector {
+	return toVectorHelper(linkedMap)
+}
+
+func (linkedMap *LinkedHashMap) Take(count int) Iterable {
+	return takeHelper(linkedMap, count)
+}
+
+func (linkedMap *LinkedHashMap) Skip(count int) Iterable {
+	return skipHelper(linkedMap, count)
+}
+
+func (linkedMap *LinkedHashMap) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(linkedMap, matchFn)
+}
+
+func (linkedMap *LinkedHashMap) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(linkedMap, groupFn)
+}
+
+func (linkedMap *LinkedHashMap) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(linkedMap, groupFn, initialValue, reducerFn)
+}
+
+func (linkedMap *LinkedHashMap) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(linkedMap, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Map with the same keys and values
+func (linkedMap *LinkedHashMap) Equals(other interface{}) bool {
+	return equalMaps(linkedMap, other)
+}
+
+func (linkedMap *LinkedHashMap) Hash() uint64 {
+	return hashOfMap(linkedMap)
+}
+
+// An Iterator over the entries of a LinkedHashMap in insertion
+// order. Yields MapEntry values.
+type LinkedHashMapIterator struct {
+	linkedMap *LinkedHashMap
+	order     Iterator
+	current   MapEntry
+	valid     bool
+}
+
+func (iterator *LinkedHashMapIterator) MoveNext() bool {
+	iterator.valid = iterator.order.MoveNext()
+	if !iterator.valid {
+		iterator.current = MapEntry{}
+		return false
+	}
+	key := iterator.order.Current().(MapEntry).Value
+	value, _ := iterator.linkedMap.Get(key)
+	iterator.current = MapEntry{
+		Key:   key,
+		Value: value,
+	}
+	return true
+}
+
+func (iterator *LinkedHashMapIterator) Current() interface{} {
+	if !iterator.valid {
+		panic(ErrIterationOutOfRange)
+	}
+	return iterator.current
+}
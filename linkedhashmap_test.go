@@ -0,0 +1,145 @@
+package collections
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func linkedKeys(m Map) []interface{} {
+	return m.Keys().ToSlice()
+}
+
+func TestLinkedHashMapIsAMap(t *testing.T) {
+	expect := expectFor(t)
+	expect(NewLinkedHashMap()).ToBeAssignableTo(reflect.TypeOf((*Map)(nil)).Elem())
+}
+
+func TestLinkedHashMapInsertionOrder(t *testing.T) {
+	expect := expectFor(t)
+	var m Map = NewLinkedHashMap()
+	expected := []interface{}{}
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key-%d", (i*37)%200)
+		expected = append(expected, key)
+		m = m.Set(key, i)
+	}
+	expect(linkedKeys(m)).ToDeepEqual(expected)
+	expect(m.(*LinkedHashMap).Size()).ToBe(200)
+
+	entries := m.ToSlice()
+	expect(entries[0]).ToBe(MapEntry{Key: "key-0", Value: 0})
+	expect(entries[1]).ToBe(MapEntry{Key: "key-37", Value: 1})
+}
+
+func TestLinkedHashMapResetKeepsPosition(t *testing.T) {
+	expect := expectFor(t)
+	m := NewLinkedHashMap().Set("a", 1).Set("b", 2).Set("c", 3)
+	updated := m.Set("a", 10)
+	expect(updated.Values().ToSlice()).ToDeepEqual([]interface{}{10, 2, 3})
+	expect(linkedKeys(updated)).ToDeepEqual([]interface{}{"a", "b", "c"})
+	expect(m.Values().ToSlice()).ToDeepEqual([]interface{}{1, 2, 3})
+	expect(m.Set("b", 2)).ToBe(m)
+}
+
+func TestLinkedHashMapRemove(t *testing.T) {
+	expect := expectFor(t)
+	m := NewLinkedHashMap().Set("a", 1).Set("b", 2).Set("c", 3)
+	removed := m.Remove("b")
+	expect(linkedKeys(removed)).ToDeepEqual([]interface{}{"a", "c"})
+	expect(removed.Contains("b")).ToBe(false)
+	expect(m.Remove("z")).ToBe(m)
+
+	// A removed key that is set again goes to the end
+	expect(linkedKeys(removed.Set("b", 4))).ToDeepEqual([]interface{}{"a", "c", "b"})
+	expect(linkedKeys(m)).ToDeepEqual([]interface{}{"a", "b", "c"})
+	expect(linkedKeys(m.Remove("a").Remove("b").Remove("c"))).ToDeepEqual([]interface{}{})
+}
+
+// Removing keys leaves no gaps to renumber, so removing from an old
+// version that many others share never rebuilds the map
+func TestLinkedHashMapRemoveFromSharedVersion(t *testing.T) {
+	expect := expectFor(t)
+	var shared Map = NewLinkedHashMap()
+	for i := 0; i < 1000; i++ {
+		shared = shared.Set(i, i)
+	}
+	for i := 0; i < 1000; i += 2 {
+		shared = shared.Remove(i)
+	}
+	order := shared.(*LinkedHashMap).order
+	expect(order.Size()).ToBe(500)
+	for i := 1; i < 1000; i += 2 {
+		removed := shared.Remove(i).(*LinkedHashMap)
+		expect(removed.Size()).ToBe(499)
+		expect(removed.next).ToBe(1000)
+		expect(removed.order.Size()).ToBe(499)
+	}
+	expected := []interface{}{}
+	for i := 1; i < 1000; i += 2 {
+		expected = append(expected, i)
+	}
+	expect(linkedKeys(shared)).ToDeepEqual(expected)
+	expect(linkedKeys(shared.Remove(1).Set(0, 0))).ToDeepEqual(concatSlices(expected[1:], []interface{}{0}))
+}
+
+func TestLinkedHashMapMatchesModel(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	var m Map = NewLinkedHashMap()
+	order := []int{}
+	values := map[int]int{}
+	for i := 0; i < 3000; i++ {
+		key := random.rand.Intn(100)
+		if random.rand.Intn(3) == 0 {
+			m = m.Remove(key)
+			if _, found := values[key]; found {
+				delete(values, key)
+				for j, k := range order {
+					if k == key {
+						order = append(order[:j], order[j+1:]...)
+						break
+					}
+				}
+			}
+		} else {
+			m = m.Set(key, i)
+			if _, found := values[key]; !found {
+				order = append(order, key)
+			}
+			values[key] = i
+		}
+	}
+	expected := []interface{}{}
+	for _, key := range order {
+		expected = append(expected, MapEntry{Key: key, Value: values[key]})
+	}
+	expect(m.ToSlice()).ToDeepEqual(expected)
+}
+
+func TestLinkedHashMapMerge(t *testing.T) {
+	expect := expectFor(t)
+	left := NewLinkedHashMap().Set("a", 1).Set("b", 2)
+	right := NewLinkedHashMap().Set("c", 30).Set("b", 20).Set("d", 40)
+	merged := left.Merge(right)
+	expect(merged.ToSlice()).ToDeepEqual([]interface{}{
+		MapEntry{Key: "a", Value: 1},
+		MapEntry{Key: "b", Value: 20},
+		MapEntry{Key: "c", Value: 30},
+		MapEntry{Key: "d", Value: 40},
+	})
+
+	summed := left.MergeWith(right, sumCounts)
+	expect(summed.Values().ToSlice()).ToDeepEqual([]interface{}{1, 22, 30, 40})
+}
+
+func TestLinkedHashMapIteratorBounds(t *testing.T) {
+	expect := expectFor(t)
+	iterator := NewLinkedHashMap().Set("a", 1).Iterator()
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+	expect(iterator.MoveNext()).ToBe(true)
+	expect(iterator.Current()).ToBe(MapEntry{Key: "a", Value: 1})
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+}
@@ -1,183 +1,279 @@
 package collections
 
-import (
-	"encoding/binary"
-	"hash/maphash"
-)
+// A HashMap is an immutable Map implemented as a hash array mapped
+// trie. Updates copy only the path from the root to the changed
+// entry, so every version of a HashMap shares most of its structure
+// with the version it was derived from.
+//
+// Keys may be of any type that can be compared with ==, or may
+// implement Hashable. See hash.go for how keys are hashed.
+//
+// HashMaps iterate over MapEntry values in an unspecified order.
+type HashMap struct {
+	hasher *keyHasher
+	size   int
+	root   HAMTNode
+}
 
-type hashKeyType = uint32
+// Returns an empty HashMap with a random seed of its own, so that no
+// two maps, and no two runs, place keys in the same slots. Maps derived
+// from one another keep its seed, so combining them shares sub-trees,
+// while combining maps built separately goes an entry at a time.
+func NewHashMap() *HashMap {
+	return newHashMapWithHasher(&keyHasher{
+		seed: randomHashSeed(),
+	})
+}
 
-const bitsPerTrieDepth hashKeyType = 5
-const sizeOfSlices hashKeyType = 32
-const bitMask hashKeyType = sizeOfSlices - 1
+// Returns an empty HashMap that hashes keys with the given seed. Unlike
+// NewHashMap, whose hashing differs from map to map, maps built with
+// the same seed and the same updates iterate in the same order in every
+// run, as long as their keys don't hash by address (pointers and
+// channels do). Maps with different seeds still combine correctly, just
+// an entry at a time rather than by sharing sub-trees.
+func NewHashMapWithSeed(seed uint64) *HashMap {
+	return newHashMapWithHasher(&keyHasher{
+		seed: fixedHashSeed(seed),
+	})
+}
 
-type HashMap struct {
-	seed maphash.Seed
-	size int
-	root HAMTNode
+func newHashMapWithHashFn(hashFn func(interface{}, hashSeed) hashKeyType) *HashMap {
+	return newHashMapWithHasher(&keyHasher{
+		seed:   randomHashSeed(),
+		hashFn: hashFn,
+	})
 }
 
-func NewHashMap() *HashMap {
-	seed := maphash.MakeSeed()
-	root := &SliceNode{
-		size: 0,
-		data: make([]HAMTNode, sizeOfSlices),
-	}
+func newHashMapWithHasher(hasher *keyHasher) *HashMap {
 	return &HashMap{
-		seed: seed,
-		size: 0,
-		root: root,
+		hasher: hasher,
+		size:   0,
+		root:   emptySliceNode,
 	}
 }
 
-func (hashMap *HashMap) Get(key interface{}) (interface{}, bool) {
-	hash := getHash(key, hashMap.seed)
-	return hashMap.root.get(hash, 1, key)
+var _ Map = (*HashMap)(nil)
+
+// A MapEntry is a single Key-Value pair. Iterating over a Map
+// yields MapEntry values.
+type MapEntry struct {
+	Key   interface{}
+	Value interface{}
 }
 
-func (hashMap *HashMap) Set(key interface{}, value interface{}) *HashMap {
-	hash := getHash(key, hashMap.seed)
-	newRoot, howManyAdded := hashMap.root.set(hash, 1, key, value)
-	return &HashMap{
-		seed: hashMap.seed,
-		size: hashMap.size + howManyAdded,
-		root: newRoot,
-	}
+// Map Methods
+
+// The number of entries in the map
+func (hashMap *HashMap) Size() int {
+	return hashMap.size
 }
 
-type HAMTNode interface {
-	set(hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) (HAMTNode, int)
-	get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool)
+func (hashMap *HashMap) Contains(key interface{}) bool {
+	_, found := hashMap.Get(key)
+	return found
 }
 
-type SliceNode struct {
-	size int
-	data []HAMTNode
+func (hashMap *HashMap) Get(key interface{}) (interface{}, bool) {
+	hash := hashMap.hasher.hash(key)
+	return hashMap.root.get(hash, 0, key)
 }
 
-type KeyValueNode struct {
-	originalHash hashKeyType
-	key          interface{}
-	value        interface{}
+func (hashMap *HashMap) Set(key interface{}, value interface{}) Map {
+	return hashMap.set(key, value)
 }
 
-func (node *KeyValueNode) get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool) {
-	if hash != node.originalHash {
-		return nil, false
-	}
-	if key != node.key {
-		return nil, false
-	}
-	return node.value, true
-}
-
-func (node *KeyValueNode) set(hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) (HAMTNode, int) {
-	// Handle a Key overwrite.
-	if node.originalHash == hash {
-		if node.key == key {
-			if node.value == value {
-				return node, 0
-			}
-
-			return &KeyValueNode{
-				originalHash: hash,
-				key:          key,
-				value:        value,
-			}, 0
-		} else {
-			panic("Need to support a hash collision node")
-		}
+func (hashMap *HashMap) set(key interface{}, value interface{}) *HashMap {
+	hash := hashMap.hasher.hash(key)
+	newRoot, howManyAdded := hashMap.root.set(nil, hash, 0, key, value)
+	return hashMap.withRoot(newRoot, hashMap.size+howManyAdded)
+}
+
+func (hashMap *HashMap) Remove(key interface{}) Map {
+	return hashMap.remove(key)
+}
+
+func (hashMap *HashMap) remove(key interface{}) *HashMap {
+	hash := hashMap.hasher.hash(key)
+	newRoot, removed := hashMap.root.remove(nil, hash, 0, key)
+	if !removed {
+		return hashMap
 	}
+	return hashMap.withRoot(newRoot, hashMap.size-1)
+}
 
-	data := make([]HAMTNode, sizeOfSlices)
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value from other wins.
+func (hashMap *HashMap) Merge(other Map) Map {
+	return hashMap.mergeWith(other, func(left *KeyValueNode, right *KeyValueNode) *KeyValueNode {
+		return right
+	})
+}
 
-	selfIndex := getIndexForHash(node.originalHash, depth)
-	newIndex := getIndexForHash(hash, depth)
-	if selfIndex != newIndex {
-		data[selfIndex] = node
-		data[newIndex] = &KeyValueNode{
-			originalHash: hash,
-			key:          key,
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value is the result of calling resolve with
+// the key, the value in this map and the value in other.
+//
+// When other is a HashMap with the same hashing, sub-trees that hold
+// keys from only one of the maps are reused as they are.
+func (hashMap *HashMap) MergeWith(other Map, resolve func(key interface{}, left interface{}, right interface{}) interface{}) Map {
+	return hashMap.mergeWith(other, func(left *KeyValueNode, right *KeyValueNode) *KeyValueNode {
+		value := resolve(left.key, left.value, right.value)
+		if sameValue(value, left.value) {
+			return left
+		}
+		if sameValue(value, right.value) {
+			return right
+		}
+		return &KeyValueNode{
+			originalHash: left.originalHash,
+			key:          left.key,
 			value:        value,
 		}
-		return &SliceNode{
-			data: data,
-			size: 2,
-		}, 1
+	})
+}
+
+func (hashMap *HashMap) mergeWith(other Map, resolve entryResolver) *HashMap {
+	if otherMap, ok := other.(*HashMap); ok {
+		if hashMap.size == 0 {
+			return otherMap
+		}
+		if sameHashing(hashMap.hasher, otherMap.hasher) {
+			return hashMap.withTrie(unionNodes(hashMap.root, otherMap.root, 0, resolve))
+		}
 	}
-	newNode, howManyAdded := node.set(hash, depth+1, key, value)
-	data[selfIndex] = newNode
-	return &SliceNode{
-		data: data,
-		size: 1,
-	}, howManyAdded
-}
-
-func (node *SliceNode) get(hash hashKeyType, depth hashKeyType, key interface{}) (interface{}, bool) {
-	index := getIndexForHash(hash, depth)
-	target := node.data[index]
-	if target == nil {
-		return nil, false
+	return other.Fold(hashMap, func(state interface{}, item interface{}) interface{} {
+		result, entry := state.(*HashMap), item.(MapEntry)
+		hash := result.hasher.hash(entry.Key)
+		right := &KeyValueNode{originalHash: hash, key: entry.Key, value: entry.Value}
+		if left := findEntry(result.root, hash, 0, entry.Key); left != nil {
+			right = resolve(left, right)
+		}
+		return result.withTrie(setEntry(result.root, 0, right))
+	}).(*HashMap)
+}
+
+// Returns a lazy Iterable over the keys of the map
+func (hashMap *HashMap) Keys() Iterable {
+	return hashMap.Map(func(entry interface{}) interface{} {
+		return entry.(MapEntry).Key
+	})
+}
+
+// Returns a lazy Iterable over the values of the map
+func (hashMap *HashMap) Values() Iterable {
+	return hashMap.Map(func(entry interface{}) interface{} {
+		return entry.(MapEntry).Value
+	})
+}
+
+// Returns the keys of the map as a Set. This is O(1) because
+// the set shares the trie of the map.
+func (hashMap *HashMap) KeySet() Set {
+	return &HashSet{
+		hashMap: hashMap,
 	}
-	return target.get(hash, depth+1, key)
-}
-
-func (node *SliceNode) set(hash hashKeyType, depth hashKeyType, key interface{}, value interface{}) (HAMTNode, int) {
-	index := getIndexForHash(hash, depth)
-	target := node.data[index]
-	if target == nil {
-		return &SliceNode{
-			size: node.size + 1,
-			data: cloneAndSet(node.data, index, &KeyValueNode{
-				originalHash: hash,
-				key:          key,
-				value:        value,
-			}),
-		}, 1
+}
+
+// Returns a map with the same hashing whose entries are those of
+// the trie rooted at root, which may be nil when empty
+func (hashMap *HashMap) withTrie(root HAMTNode) *HashMap {
+	if root == nil {
+		root = emptySliceNode
 	}
-	newNode, howManyAdded := target.set(hash, depth+1, key, value)
-	return &SliceNode{
-		size: node.size,
-		data: cloneAndSet(node.data, index, newNode),
-	}, howManyAdded
-}
-
-func getIndexForHash(hash hashKeyType, depth hashKeyType) hashKeyType {
-	return hash >> depth * bitsPerTrieDepth & bitMask
-}
-
-func getHash(v interface{}, seed maphash.Seed) hashKeyType {
-	var h maphash.Hash
-	h.SetSeed(seed)
-	switch v := v.(type) {
-	case string:
-		h.WriteString(v)
-	case int32:
-		buffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(buffer, uint32(v))
-		h.Write(buffer)
-	case uint32:
-		buffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(buffer, v)
-		h.Write(buffer)
-	case int64:
-		buffer := make([]byte, 8)
-		binary.LittleEndian.PutUint64(buffer, uint64(v))
-		h.Write(buffer)
-	case uint64:
-		buffer := make([]byte, 8)
-		binary.LittleEndian.PutUint64(buffer, v)
-		h.Write(buffer)
-	default:
-		panic(ErrUnhashableType)
+	if root == hashMap.root {
+		return hashMap
 	}
+	return hashMap.withRoot(root, root.entryCount())
+}
 
-	return hashKeyType(h.Sum64())
+func (hashMap *HashMap) withRoot(root HAMTNode, size int) *HashMap {
+	return &HashMap{
+		hasher: hashMap.hasher,
+		size:   size,
+		root:   root,
+	}
+}
+
+// Iterable Methods
+
+func (hashMap *HashMap) Iterator() Iterator {
+	return &HashMapIterator{
+		trie: newTrieIterator(hashMap.root),
+	}
+}
+
+func (hashMap *HashMap) ForEach(iterFn func(interface{})) {
+	forEachHelper(hashMap, iterFn)
+}
+
+func (hashMap *HashMap) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(hashMap, mapFn)
+}
+
+func (hashMap *HashMap) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(hashMap, filterFn)
+}
+
+func (hashMap *HashMap) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(hashMap, initialValue, reducerFn)
+}
+
+func (hashMap *HashMap) ToSlice() []interface{} {
+	return toSliceHelper(hashMap)
 }
 
-func cloneAndSet(data []HAMTNode, index hashKeyType, node HAMTNode) []HAMTNode {
-	newSlice := make([]HAMTNode, sizeOfSlices)
-	copy(newSlice, data)
-	newSlice[index] = node
-	return newSlice
+func (hashMap *HashMap) ToVector() *Vector {
+	return toVectorHelper(hashMap)
+}
+
+func (hashMap *HashMap) Take(count int) Iterable {
+	return takeHelper(hashMap, count)
+}
+
+func (hashMap *HashMap) Skip(count int) Iterable {
+	return skipHelper(hashMap, count)
+}
+
+func (hashMap *HashMap) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(hashMap, matchFn)
+}
+
+func (hashMap *HashMap) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(hashMap, groupFn)
+}
+
+func (hashMap *HashMap) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(hashMap, groupFn, initialValue, reducerFn)
+}
+
+func (hashMap *HashMap) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(hashMap, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Map with the same keys and values
+func (hashMap *HashMap) Equals(other interface{}) bool {
+	return equalMaps(hashMap, other)
+}
+
+func (hashMap *HashMap) Hash() uint64 {
+	return hashOfMap(hashMap)
+}
+
+// An Iterator over the entries of a HashMap. Yields MapEntry values.
+type HashMapIterator struct {
+	trie *trieIterator
+}
+
+func (iterator *HashMapIterator) MoveNext() bool {
+	return iterator.trie.MoveNext()
+}
+
+func (iterator *HashMapIterator) Current() interface{} {
+	entry := iterator.trie.current()
+	return MapEntry{
+		Key:   entry.key,
+		Value: entry.value,
+	}
 }
@@ -1,6 +1,10 @@
 package collections
 
 import (
+	"fmt"
+	"math/bits"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -34,7 +38,7 @@ func TestHashMapSet(t *testing.T) {
 func TestHashMapManyValues(t *testing.T) {
 	expect := expectFor(t)
 	random := fakerFor(t)
-	hashMap := NewHashMap()
+	var hashMap Map = NewHashMap()
 	goMap := map[string]string{}
 	for i := 0; i < 2000; i++ {
 		key := random.String(4, 30)
@@ -48,3 +52,512 @@ func TestHashMapManyValues(t *testing.T) {
 		expect(found).ToBe(true)
 	}
 }
+
+// A deliberately weak hash which only looks at the first character
+// of a string key, so that keys sharing a first character always collide
+func firstCharacterHash(key interface{}, seed hashSeed) hashKeyType {
+	return getHash(key.(string)[:1], seed)
+}
+
+func TestHashMapCollisions(t *testing.T) {
+	expect := expectFor(t)
+	m0 := newHashMapWithHashFn(firstCharacterHash)
+	m1 := m0.set("apple", 1)
+	m2 := m1.set("avocado", 2)
+	m3 := m2.set("apricot", 3)
+	m4 := m3.set("banana", 4)
+	m5 := m4.set("avocado", 5)
+
+	expect(m1.size).ToBe(1)
+	expect(m2.size).ToBe(2)
+	expect(m3.size).ToBe(3)
+	expect(m4.size).ToBe(4)
+	expect(m5.size).ToBe(4)
+
+	val, found := m5.Get("avocado")
+	expect(val).ToBe(5)
+	expect(found).ToBe(true)
+
+	val, found = m4.Get("avocado")
+	expect(val).ToBe(2)
+	expect(found).ToBe(true)
+
+	val, found = m5.Get("apple")
+	expect(val).ToBe(1)
+	expect(found).ToBe(true)
+
+	val, found = m5.Get("banana")
+	expect(val).ToBe(4)
+	expect(found).ToBe(true)
+
+	val, found = m5.Get("almond")
+	expect(val).ToBe(nil)
+	expect(found).ToBe(false)
+
+	val, found = m1.Get("apricot")
+	expect(val).ToBe(nil)
+	expect(found).ToBe(false)
+}
+
+func TestHashMapCollisionOverwriteWithSameValue(t *testing.T) {
+	expect := expectFor(t)
+	m0 := newHashMapWithHashFn(firstCharacterHash).set("apple", 1).set("avocado", 2)
+	m1 := m0.set("avocado", 2)
+	expect(m1.size).ToBe(2)
+	val, found := m1.Get("avocado")
+	expect(val).ToBe(2)
+	expect(found).ToBe(true)
+}
+
+func TestHashMapManyCollisions(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	hashMap := newHashMapWithHashFn(firstCharacterHash)
+	goMap := map[string]string{}
+	for i := 0; i < 2000; i++ {
+		key := random.String(4, 30)
+		value := random.String(4, 30)
+		goMap[key] = value
+		hashMap = hashMap.set(key, value)
+	}
+	expect(hashMap.size).ToBe(len(goMap))
+	for k, v := range goMap {
+		val, found := hashMap.Get(k)
+		expect(val).ToBe(v)
+		expect(found).ToBe(true)
+	}
+}
+
+func TestHashMapIsAMap(t *testing.T) {
+	expect := expectFor(t)
+	expect(NewHashMap()).ToBeAssignableTo(reflect.TypeOf((*Map)(nil)).Elem())
+}
+
+func TestHashMapContains(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().Set("a", 1).Set("b", nil)
+	expect(m.Contains("a")).ToBe(true)
+	expect(m.Contains("b")).ToBe(true)
+	expect(m.Contains("c")).ToBe(false)
+}
+
+func TestHashMapRemove(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewHashMap().Set("a", 1).Set("b", 2).Set("c", 3)
+	m1 := m0.Remove("b")
+
+	expect(m1.Contains("b")).ToBe(false)
+	expect(m1.Contains("a")).ToBe(true)
+	expect(m1.Contains("c")).ToBe(true)
+	expect(m1.(*HashMap).Size()).ToBe(2)
+	expect(m0.Contains("b")).ToBe(true)
+	expect(m0.(*HashMap).Size()).ToBe(3)
+
+	m2 := m1.Remove("b")
+	expect(m2.(*HashMap).Size()).ToBe(2)
+}
+
+func TestHashMapRemoveCollisions(t *testing.T) {
+	expect := expectFor(t)
+	m0 := newHashMapWithHashFn(firstCharacterHash).set("apple", 1).set("avocado", 2).set("apricot", 3)
+	m1 := m0.remove("avocado")
+	m2 := m1.remove("apple")
+	m3 := m2.remove("apricot")
+
+	expect(m1.Size()).ToBe(2)
+	expect(m2.Size()).ToBe(1)
+	expect(m3.Size()).ToBe(0)
+	expect(m1.Contains("avocado")).ToBe(false)
+	expect(m1.Contains("apple")).ToBe(true)
+	expect(m2.Contains("apricot")).ToBe(true)
+	expect(m3.Contains("apricot")).ToBe(false)
+	expect(m0.Contains("avocado")).ToBe(true)
+}
+
+func TestHashMapIteration(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	for _, hashMap := range []*HashMap{NewHashMap(), newHashMapWithHashFn(firstCharacterHash)} {
+		goMap := map[string]string{}
+		for i := 0; i < 500; i++ {
+			key := random.String(4, 30)
+			value := random.String(4, 30)
+			goMap[key] = value
+			hashMap = hashMap.set(key, value)
+		}
+
+		seen := map[interface{}]interface{}{}
+		hashMap.ForEach(func(v interface{}) {
+			entry := v.(MapEntry)
+			seen[entry.Key] = entry.Value
+		})
+		expect(len(seen)).ToBe(len(goMap))
+		for k, v := range goMap {
+			expect(seen[k]).ToBe(v)
+		}
+		expect(len(hashMap.ToSlice())).ToBe(len(goMap))
+	}
+}
+
+func TestHashMapEmptyIteration(t *testing.T) {
+	expect := expectFor(t)
+	hashMap := NewHashMap()
+	iterator := hashMap.Iterator()
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+	expect(hashMap.ToSlice()).ToDeepEqual([]interface{}{})
+}
+
+func TestHashMapKeysAndValues(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().Set("a", 1).Set("b", 2).Set("c", 3)
+
+	keys := m.Keys().ToSlice()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].(string) < keys[j].(string) })
+	expect(keys).ToDeepEqual([]interface{}{"a", "b", "c"})
+
+	values := m.Values().ToSlice()
+	sort.Slice(values, func(i, j int) bool { return values[i].(int) < values[j].(int) })
+	expect(values).ToDeepEqual([]interface{}{1, 2, 3})
+}
+
+func TestHashMapKeySet(t *testing.T) {
+	expect := expectFor(t)
+	m := NewHashMap().Set("a", 1).Set("b", 2)
+	keySet := m.KeySet()
+	expect(keySet.Size()).ToBe(2)
+	expect(keySet.Contains("a")).ToBe(true)
+	expect(keySet.Contains("b")).ToBe(true)
+	expect(keySet.Contains("c")).ToBe(false)
+}
+
+func TestHashMapMerge(t *testing.T) {
+	expect := expectFor(t)
+	left := NewHashMap().Set("a", 1).Set("b", 2)
+	right := NewHashMap().Set("b", 20).Set("c", 30)
+	merged := left.Merge(right)
+
+	expect(merged.(*HashMap).Size()).ToBe(3)
+	val, _ := merged.Get("a")
+	expect(val).ToBe(1)
+	val, _ = merged.Get("b")
+	expect(val).ToBe(20)
+	val, _ = merged.Get("c")
+	expect(val).ToBe(30)
+	expect(left.(*HashMap).Size()).ToBe(2)
+}
+
+func TestHashMapRemoveMissingKeyReturnsSameMap(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewHashMap().Set("a", 1).Set("b", 2)
+	expect(m0.Remove("c")).ToBe(m0)
+	empty := NewHashMap()
+	expect(empty.Remove("a")).ToBe(empty)
+}
+
+func TestHashMapRemoveEverything(t *testing.T) {
+	expect := expectFor(t)
+	var m Map = NewHashMap()
+	keys := []string{}
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		keys = append(keys, key)
+		m = m.Set(key, i)
+	}
+	for _, key := range keys {
+		m = m.Remove(key)
+		expectCompactTrie(t, m.(*HashMap).root, 0)
+	}
+	expect(m.(*HashMap).Size()).ToBe(0)
+	expect(m.ToSlice()).ToDeepEqual([]interface{}{})
+}
+
+func TestHashMapRandomSetAndRemove(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	for _, hashMap := range []*HashMap{NewHashMap(), newHashMapWithHashFn(firstCharacterHash)} {
+		goMap := map[string]string{}
+		keys := []string{}
+		for i := 0; i < 5000; i++ {
+			if len(keys) > 0 && random.rand.Intn(3) == 0 {
+				key := keys[random.rand.Intn(len(keys))]
+				delete(goMap, key)
+				hashMap = hashMap.remove(key)
+			} else {
+				key := random.String(1, 8)
+				value := random.String(1, 8)
+				keys = append(keys, key)
+				goMap[key] = value
+				hashMap = hashMap.set(key, value)
+			}
+			expect(hashMap.Size()).ToBe(len(goMap))
+		}
+
+		expectCompactTrie(t, hashMap.root, 0)
+		for _, k := range keys {
+			expected, expectedFound := goMap[k]
+			val, found := hashMap.Get(k)
+			expect(found).ToBe(expectedFound)
+			if expectedFound {
+				expect(val).ToBe(expected)
+			}
+		}
+		expect(len(hashMap.ToSlice())).ToBe(len(goMap))
+	}
+}
+
+// Fails the test if the bitmaps or size of any SliceNode disagree
+// with its contents, or if any SliceNode below the root is empty or holds a
+// lone entry that should have been moved into its parent
+func expectCompactTrie(t *testing.T, node HAMTNode, depth hashKeyType) {
+	t.Helper()
+	sliceNode, ok := node.(*SliceNode)
+	if !ok {
+		return
+	}
+	if sliceNode.dataMap&sliceNode.nodeMap != 0 {
+		t.Fatalf("slice node at depth %d has a slot marked as both entry and node", depth)
+	}
+	if bits.OnesCount32(sliceNode.dataMap) != len(sliceNode.entries) {
+		t.Fatalf("slice node at depth %d has a data map that disagrees with its entries", depth)
+	}
+	if bits.OnesCount32(sliceNode.nodeMap) != len(sliceNode.nodes) {
+		t.Fatalf("slice node at depth %d has a node map that disagrees with its nodes", depth)
+	}
+	size := len(sliceNode.entries)
+	for _, child := range sliceNode.nodes {
+		expectCompactTrie(t, child, depth+1)
+		size += child.entryCount()
+	}
+	if size != sliceNode.size {
+		t.Fatalf("slice node at depth %d has size %d but holds %d entries", depth, sliceNode.size, size)
+	}
+	if depth > 0 && len(sliceNode.entries)+len(sliceNode.nodes) == 0 {
+		t.Fatalf("empty slice node at depth %d", depth)
+	}
+	if _, ok := singleEntry(sliceNode); depth > 0 && ok {
+		t.Fatalf("slice node at depth %d holds a single entry", depth)
+	}
+}
+
+func TestHashMapSetSameValueSharesRoot(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewHashMap().set("a", 1).set("b", 2)
+	m1 := m0.set("b", 2)
+	expect(m1.root).ToBe(m0.root)
+}
+
+func TestHashMapUncomparableValues(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewHashMap().Set("a", []int{1})
+	m1 := m0.Set("a", []int{2})
+	val, _ := m1.Get("a")
+	expect(val).ToDeepEqual([]int{2})
+	val, _ = m0.Get("a")
+	expect(val).ToDeepEqual([]int{1})
+}
+
+// A comparable type whose values may still panic when compared with ==
+type valueHolder struct {
+	value interface{}
+}
+
+func TestHashMapValuesHoldingSlices(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewHashMap().Set("k", valueHolder{[]int{1}})
+	m1 := m0.Set("k", valueHolder{[]int{2}})
+	val, _ := m1.Get("k")
+	expect(val).ToDeepEqual(valueHolder{[]int{2}})
+	merged := m0.MergeWith(m1, func(key interface{}, left interface{}, right interface{}) interface{} {
+		return right
+	})
+	val, _ = merged.Get("k")
+	expect(val).ToDeepEqual(valueHolder{[]int{2}})
+	linked := NewLinkedHashMap().Set("k", valueHolder{[]int{1}}).Set("k", valueHolder{[]int{2}})
+	val, _ = linked.Get("k")
+	expect(val).ToDeepEqual(valueHolder{[]int{2}})
+}
+
+// A composite key which can't be hashed by the built in hashing
+type compositeID struct {
+	tenant string
+	ids    []int64
+}
+
+func (id compositeID) Hash() uint64 {
+	hash := uint64(len(id.tenant))
+	for _, part := range id.ids {
+		hash = hash*31 + uint64(part)
+	}
+	return hash
+}
+
+func (id compositeID) Equals(other interface{}) bool {
+	otherID, ok := other.(compositeID)
+	if !ok || otherID.tenant != id.tenant || len(otherID.ids) != len(id.ids) {
+		return false
+	}
+	for i := range id.ids {
+		if id.ids[i] != otherID.ids[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHashMapHashableKeys(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewHashMap().Set(compositeID{"acme", []int64{1, 2}}, "first")
+	m1 := m0.Set(compositeID{"acme", []int64{1, 3}}, "second")
+	m2 := m1.Set(compositeID{"acme", []int64{1, 2}}, "replaced")
+
+	val, found := m2.Get(compositeID{"acme", []int64{1, 2}})
+	expect(val).ToBe("replaced")
+	expect(found).ToBe(true)
+	val, found = m2.Get(compositeID{"acme", []int64{1, 3}})
+	expect(val).ToBe("second")
+	expect(found).ToBe(true)
+	expect(m2.Contains(compositeID{"other", []int64{1, 2}})).ToBe(false)
+	expect(m2.(*HashMap).Size()).ToBe(2)
+
+	m3 := m2.Remove(compositeID{"acme", []int64{1, 3}})
+	expect(m3.Contains(compositeID{"acme", []int64{1, 3}})).ToBe(false)
+	expect(m3.(*HashMap).Size()).ToBe(1)
+}
+
+// Hashable keys whose hashes always collide
+type collidingKey struct {
+	id int64
+}
+
+func (key collidingKey) Hash() uint64 {
+	return 7
+}
+
+func (key collidingKey) Equals(other interface{}) bool {
+	otherKey, ok := other.(collidingKey)
+	return ok && otherKey.id == key.id
+}
+
+func TestHashMapCollidingHashableKeys(t *testing.T) {
+	expect := expectFor(t)
+	var m Map = NewHashMap()
+	for i := int64(0); i < 50; i++ {
+		m = m.Set(collidingKey{i}, i)
+	}
+	expect(m.(*HashMap).Size()).ToBe(50)
+	for i := int64(0); i < 50; i++ {
+		val, found := m.Get(collidingKey{i})
+		expect(val).ToBe(i)
+		expect(found).ToBe(true)
+	}
+	for i := int64(0); i < 50; i += 2 {
+		m = m.Remove(collidingKey{i})
+	}
+	expect(m.(*HashMap).Size()).ToBe(25)
+	expect(m.Contains(collidingKey{2})).ToBe(false)
+	expect(m.Contains(collidingKey{3})).ToBe(true)
+}
+
+func TestHashMapMergeWith(t *testing.T) {
+	expect := expectFor(t)
+	left := NewHashMap().Set("a", 1).Set("b", 2)
+	right := NewHashMap().Set("b", 20).Set("c", 30)
+	merged := left.MergeWith(right, func(key interface{}, l interface{}, r interface{}) interface{} {
+		expect(key).ToBe("b")
+		return l.(int) + r.(int)
+	})
+
+	expect(merged.(*HashMap).Size()).ToBe(3)
+	val, _ := merged.Get("a")
+	expect(val).ToBe(1)
+	val, _ = merged.Get("b")
+	expect(val).ToBe(22)
+	val, _ = merged.Get("c")
+	expect(val).ToBe(30)
+}
+
+func sumCounts(key interface{}, left interface{}, right interface{}) interface{} {
+	return left.(int) + right.(int)
+}
+
+func TestHashMapMergeWithMatchesModel(t *testing.T) {
+	random := fakerFor(t)
+	shared := &keyHasher{seed: randomHashSeed()}
+	hashers := map[string][2]*keyHasher{
+		"shared":     {shared, shared},
+		"collisions": {{seed: randomHashSeed(), hashFn: moduloSixteenHash}, nil},
+		"mismatched": {shared, {seed: randomHashSeed(), hashFn: moduloSixteenHash}},
+	}
+	for name, pair := range hashers {
+		if pair[1] == nil {
+			pair[1] = pair[0]
+		}
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				left, right := newHashMapWithHasher(pair[0]), newHashMapWithHasher(pair[1])
+				model := map[int]int{}
+				for j := random.rand.Intn(300); j > 0; j-- {
+					key := random.rand.Intn(500)
+					left = left.set(key, j)
+					model[key] = j
+				}
+				rightModel := map[int]int{}
+				for j := random.rand.Intn(300); j > 0; j-- {
+					key := random.rand.Intn(500)
+					right = right.set(key, j)
+					rightModel[key] = j
+				}
+				for key, count := range rightModel {
+					model[key] += count
+				}
+
+				merged := left.MergeWith(right, sumCounts).(*HashMap)
+				expect := expectFor(t)
+				expect(merged.Size()).ToBe(len(model))
+				for key, count := range model {
+					val, found := merged.Get(key)
+					expect(found).ToBe(true)
+					expect(val).ToBe(count)
+				}
+				expectCompactTrie(t, merged.root, 0)
+
+				// Merging a map with itself still resolves every key
+				doubled := left.MergeWith(left, sumCounts)
+				left.ForEach(func(item interface{}) {
+					entry := item.(MapEntry)
+					val, _ := doubled.Get(entry.Key)
+					expect(val).ToBe(2 * entry.Value.(int))
+				})
+			}
+		})
+	}
+}
+
+func TestHashMapMergeSharesStructure(t *testing.T) {
+	expect := expectFor(t)
+	left := NewHashMap()
+	for i := 0; i < 1000; i++ {
+		left = left.set(i, i)
+	}
+	right := NewHashMap().set(5000, 5000)
+
+	expect(left.Merge(NewHashMap())).ToBe(left)
+	expect(left.Merge(left)).ToBe(left)
+	expect(NewHashMap().Merge(left)).ToBe(left)
+	keepLeft := func(key interface{}, l interface{}, r interface{}) interface{} { return l }
+	expect(left.MergeWith(left.set(7, 70), keepLeft)).ToBe(left)
+
+	merged := left.Merge(right).(*HashMap)
+	expect(merged.Size()).ToBe(1001)
+	mergedRoot, leftRoot := merged.root.(*SliceNode), left.root.(*SliceNode)
+	shared := 0
+	for i := range mergedRoot.nodes {
+		for j := range leftRoot.nodes {
+			if mergedRoot.nodes[i] == leftRoot.nodes[j] {
+				shared++
+			}
+		}
+	}
+	expect(shared >= len(leftRoot.nodes)-1).ToBe(true)
+}
@@ -0,0 +1,207 @@
+package collections
+
+// A MultiMap is an immutable map from each key to a set of values.
+// It is a HashMap whose values are non-empty HashSets: putting a value
+// under a new key creates its set, and removing the last value of a
+// key removes the key, so there are never empty sets to check for.
+//
+// Iterating over a MultiMap yields a MapEntry for every key-value pair,
+// in an unspecified order.
+type MultiMap struct {
+	sets *HashMap
+	size int
+}
+
+var _ FiniteIterable = (*MultiMap)(nil)
+
+// Factory for MultiMaps
+func NewMultiMap() *MultiMap {
+	return &MultiMap{
+		sets: NewHashMap(),
+		size: 0,
+	}
+}
+
+// The number of key-value pairs in the map
+func (multiMap *MultiMap) Size() int {
+	return multiMap.size
+}
+
+// The number of distinct keys in the map
+func (multiMap *MultiMap) KeyCount() int {
+	return multiMap.sets.size
+}
+
+// The number of values for the key
+func (multiMap *MultiMap) Count(key interface{}) int {
+	set, found := multiMap.sets.Get(key)
+	if !found {
+		return 0
+	}
+	return set.(*HashSet).Size()
+}
+
+// Returns true if the key has at least one value
+func (multiMap *MultiMap) Contains(key interface{}) bool {
+	return multiMap.sets.Contains(key)
+}
+
+// Returns true if the value is one of the values for the key
+func (multiMap *MultiMap) ContainsEntry(key interface{}, value interface{}) bool {
+	set, found := multiMap.sets.Get(key)
+	return found && set.(*HashSet).Contains(value)
+}
+
+// Returns the values for the key, which are empty if the key
+// is not in the map
+func (multiMap *MultiMap) GetAll(key interface{}) Iterable {
+	set, found := multiMap.sets.Get(key)
+	if !found {
+		return NewEmptyIterable()
+	}
+	return set.(*HashSet)
+}
+
+// Returns a map with the value added to the values for the key.
+// Returns the same map if the value is already there.
+func (multiMap *MultiMap) Put(key interface{}, value interface{}) *MultiMap {
+	set, found := multiMap.sets.Get(key)
+	if !found {
+		return multiMap.withSets(multiMap.sets.set(key, NewHashSet(value)), multiMap.size+1)
+	}
+	newSet := set.(*HashSet).Add(value)
+	if newSet == set {
+		return multiMap
+	}
+	return multiMap.withSets(multiMap.sets.set(key, newSet), multiMap.size+1)
+}
+
+// Returns a map without the value among the values for the key.
+// If it was the only value, the key is removed as well.
+func (multiMap *MultiMap) RemoveValue(key interface{}, value interface{}) *MultiMap {
+	set, found := multiMap.sets.Get(key)
+	if !found {
+		return multiMap
+	}
+	newSet := set.(*HashSet).Remove(value)
+	if newSet == set {
+		return multiMap
+	}
+	if newSet.Size() == 0 {
+		return multiMap.withSets(multiMap.sets.remove(key), multiMap.size-1)
+	}
+	return multiMap.withSets(multiMap.sets.set(key, newSet), multiMap.size-1)
+}
+
+// Returns a map without the key and all of its values
+func (multiMap *MultiMap) RemoveAll(key interface{}) *MultiMap {
+	count := multiMap.Count(key)
+	if count == 0 {
+		return multiMap
+	}
+	return multiMap.withSets(multiMap.sets.remove(key), multiMap.size-count)
+}
+
+// Returns a lazy Iterable over the distinct keys of the map
+func (multiMap *MultiMap) Keys() Iterable {
+	return multiMap.sets.Keys()
+}
+
+// Returns the distinct keys of the map as a Set in O(1)
+func (multiMap *MultiMap) KeySet() Set {
+	return multiMap.sets.KeySet()
+}
+
+func (multiMap *MultiMap) withSets(sets *HashMap, size int) *MultiMap {
+	return &MultiMap{
+		sets: sets,
+		size: size,
+	}
+}
+
+// Iterable Methods
+
+func (multiMap *MultiMap) Iterator() Iterator {
+	return &MultiMapIterator{
+		sets: multiMap.sets.Iterator(),
+	}
+}
+
+func (multiMap *MultiMap) ForEach(iterFn func(interface{})) {
+	forEachHelper(multiMap, iterFn)
+}
+
+func (multiMap *MultiMap) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(multiMap, mapFn)
+}
+
+func (multiMap *MultiMap) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(multiMap, filterFn)
+}
+
+func (multiMap *MultiMap) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(multiMap, initialValue, reducerFn)
+}
+
+func (multiMap *MultiMap) ToSlice() []interface{} {
+	return toSliceHelper(multiMap)
+}
+
+func (multiMap *MultiMap) ToVector() *Vector {
+	return toVectorHelper(multiMap)
+}
+
+func (multiMap *MultiMap) Take(count int) Iterable {
+	return takeHelper(multiMap, count)
+}
+
+func (multiMap *MultiMap) Skip(count int) Iterable {
+	return skipHelper(multiMap, count)
+}
+
+func (multiMap *MultiMap) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(multiMap, matchFn)
+}
+
+func (multiMap *MultiMap) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(multiMap, groupFn)
+}
+
+func (multiMap *MultiMap) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(multiMap, groupFn, initialValue, reducerFn)
+}
+
+func (multiMap *MultiMap) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(multiMap, matchFn)
+}
+
+// An Iterator over every key-value pair of a MultiMap.
+// Yields MapEntry values.
+type MultiMapIterator struct {
+	sets   Iterator
+	key    interface{}
+	values Iterator
+}
+
+func (iterator *MultiMapIterator) MoveNext() bool {
+	for iterator.values == nil || !iterator.values.MoveNext() {
+		if !iterator.sets.MoveNext() {
+			iterator.values = nil
+			return false
+		}
+		entry := iterator.sets.Current().(MapEntry)
+		iterator.key = entry.Key
+		iterator.values = entry.Value.(*HashSet).Iterator()
+	}
+	return true
+}
+
+func (iterator *MultiMapIterator) Current() interface{} {
+	if iterator.values == nil {
+		panic(ErrIterationOutOfRange)
+	}
+	return MapEntry{
+		Key:   iterator.key,
+		Value: iterator.values.Current(),
+	}
+}
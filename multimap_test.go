@@ -0,0 +1,86 @@
+package collections
+
+import (
+	"sort"
+	"testing"
+)
+
+func sortedInts(iterable Iterable) []int {
+	result := []int{}
+	iterable.ForEach(func(value interface{}) {
+		result = append(result, value.(int))
+	})
+	sort.Ints(result)
+	return result
+}
+
+func TestMultiMapPutAndGetAll(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewMultiMap()
+	m1 := m0.Put("a", 1).Put("a", 2).Put("b", 3)
+	expect(sortedInts(m1.GetAll("a"))).ToDeepEqual([]int{1, 2})
+	expect(sortedInts(m1.GetAll("b"))).ToDeepEqual([]int{3})
+	expect(sortedInts(m1.GetAll("c"))).ToDeepEqual([]int{})
+	expect(m1.Size()).ToBe(3)
+	expect(m1.KeyCount()).ToBe(2)
+	expect(m1.Count("a")).ToBe(2)
+	expect(m1.Count("c")).ToBe(0)
+	expect(m1.ContainsEntry("a", 2)).ToBe(true)
+	expect(m1.ContainsEntry("b", 2)).ToBe(false)
+	expect(m0.Size()).ToBe(0)
+	expect(m1.Put("a", 1)).ToBe(m1)
+}
+
+func TestMultiMapRemoveValue(t *testing.T) {
+	expect := expectFor(t)
+	m := NewMultiMap().Put("a", 1).Put("a", 2).Put("b", 3)
+	removed := m.RemoveValue("a", 1)
+	expect(sortedInts(removed.GetAll("a"))).ToDeepEqual([]int{2})
+	expect(removed.Size()).ToBe(2)
+	expect(m.RemoveValue("a", 5)).ToBe(m)
+	expect(m.RemoveValue("z", 5)).ToBe(m)
+
+	// Removing the last value removes the key
+	removed = removed.RemoveValue("a", 2)
+	expect(removed.Contains("a")).ToBe(false)
+	expect(removed.KeyCount()).ToBe(1)
+	expect(removed.Size()).ToBe(1)
+	expect(removed.RemoveValue("b", 3).Size()).ToBe(0)
+}
+
+func TestMultiMapRemoveAll(t *testing.T) {
+	expect := expectFor(t)
+	m := NewMultiMap().Put("a", 1).Put("a", 2).Put("b", 3)
+	removed := m.RemoveAll("a")
+	expect(removed.Contains("a")).ToBe(false)
+	expect(removed.Size()).ToBe(1)
+	expect(removed.KeyCount()).ToBe(1)
+	expect(m.RemoveAll("z")).ToBe(m)
+}
+
+func TestMultiMapIteration(t *testing.T) {
+	expect := expectFor(t)
+	m := NewMultiMap()
+	expected := map[MapEntry]bool{}
+	for key := 0; key < 20; key++ {
+		for value := 0; value < key%4; value++ {
+			m = m.Put(key, value)
+			expected[MapEntry{Key: key, Value: value}] = true
+		}
+	}
+	entries := m.ToSlice()
+	expect(len(entries)).ToBe(len(expected))
+	expect(m.Size()).ToBe(len(expected))
+	for _, entry := range entries {
+		expect(expected[entry.(MapEntry)]).ToBe(true)
+	}
+	expect(sortedInts(m.Keys())).ToDeepEqual(sortedInts(m.KeySet()))
+	expect(NewMultiMap().ToSlice()).ToDeepEqual([]interface{}{})
+
+	iterator := NewMultiMap().Put("a", 1).Iterator()
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+	expect(iterator.MoveNext()).ToBe(true)
+	expect(iterator.Current()).ToBe(MapEntry{Key: "a", Value: 1})
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+}
@@ -48,6 +48,10 @@ func (rng *Range) ToSlice() []interface{} {
 	return slice
 }
 
+func (rng *Range) ToVector() *Vector {
+	return vectorFromSlice(rng.ToSlice())
+}
+
 func (rng *Range) Map(mapFn func(interface{}) interface{}) Iterable {
 	return mapHelper(rng, mapFn)
 }
@@ -84,6 +88,14 @@ func (rng *Range) SkipWhile(matchFn func(interface{}) bool) Iterable {
 	return skipWhileHelper(rng, matchFn)
 }
 
+func (rng *Range) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(rng, groupFn)
+}
+
+func (rng *Range) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(rng, groupFn, initialValue, reducerFn)
+}
+
 func (rng *Range) Any(matchFn func(interface{}) bool) bool {
 	for i := rng.begin; i < rng.end; i++ {
 		if matchFn(i) {
@@ -97,3 +97,22 @@ func TestRangeSkipWhile(t *testing.T) {
 	actual := rng.SkipWhile(func(v interface{}) bool { return v.(int) < 5 }).ToSlice()
 	expect(actual).ToDeepEqual(expected)
 }
+
+func TestRangeGroupBy(t *testing.T) {
+	expect := expectFor(t)
+	groups := NewRange(0, 10).GroupBy(func(v interface{}) interface{} {
+		return v.(int) % 3
+	})
+	group, _ := groups.Get(0)
+	expect(group.(Sequence).ToSlice()).ToDeepEqual([]interface{}{0, 3, 6, 9})
+	group, _ = groups.Get(2)
+	expect(group.(Sequence).ToSlice()).ToDeepEqual([]interface{}{2, 5, 8})
+
+	counts := NewRange(0, 10).GroupByAggregate(func(v interface{}) interface{} {
+		return v.(int)%2 == 0
+	}, 0, func(count interface{}, v interface{}) interface{} {
+		return count.(int) + 1
+	})
+	count, _ := counts.Get(true)
+	expect(count).ToBe(5)
+}
@@ -7,7 +7,8 @@ package collections
 // they reduce to slice indexing.
 // Immutable data operations on SliceSequences are inefficient
 // (O(n) for Update, Append, Prepend) because SliceSequences do not
-// support data reuse.
+// support data reuse. Slice is the exception: slices of a SliceSequence
+// share its underlying slice.
 type SliceSequence struct {
 	slice []interface{}
 }
@@ -45,6 +46,10 @@ func (sliceSequence *SliceSequence) ToSlice() []interface{} {
 	return toSliceHelper(sliceSequence)
 }
 
+func (sliceSequence *SliceSequence) ToVector() *Vector {
+	return toVectorHelper(sliceSequence)
+}
+
 func (sliceSequence *SliceSequence) Take(count int) Iterable {
 	return takeHelper(sliceSequence, count)
 }
@@ -57,10 +62,29 @@ func (sliceSequence *SliceSequence) SkipWhile(matchFn func(interface{}) bool) It
 	return skipWhileHelper(sliceSequence, matchFn)
 }
 
+func (sliceSequence *SliceSequence) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(sliceSequence, groupFn)
+}
+
+func (sliceSequence *SliceSequence) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(sliceSequence, groupFn, initialValue, reducerFn)
+}
+
 func (sliceSequence *SliceSequence) Any(matchFn func(interface{}) bool) bool {
 	return anyHelper(sliceSequence, matchFn)
 }
 
+// Hashable Methods
+
+// Returns true if other is a Sequence with the same elements in the same order
+func (sliceSequence *SliceSequence) Equals(other interface{}) bool {
+	return equalSequences(sliceSequence, other)
+}
+
+func (sliceSequence *SliceSequence) Hash() uint64 {
+	return hashOfSequence(sliceSequence)
+}
+
 // Sequence Methods
 func (sliceSequence *SliceSequence) Size() int {
 	return len(sliceSequence.slice)
@@ -92,6 +116,22 @@ func (sliceSequence *SliceSequence) Update(index int, value interface{}) Sequenc
 	return NewSliceSequence(newSlice...)
 }
 
+func (sliceSequence *SliceSequence) Prepend(value interface{}) Sequence {
+	newSlice := make([]interface{}, len(sliceSequence.slice)+1)
+	newSlice[0] = value
+	copy(newSlice[1:], sliceSequence.slice)
+	return NewSliceSequence(newSlice...)
+}
+
+// Slices in O(1). The new sequence shares the underlying slice
+// rather than copying it.
+func (sliceSequence *SliceSequence) Slice(start int, end int) Sequence {
+	if start < 0 || end > sliceSequence.Size() || start > end {
+		panic(ErrIndexOutOfRange)
+	}
+	return NewSliceSequence(sliceSequence.slice[start:end:end]...)
+}
+
 type SliceIterator struct {
 	slice []interface{}
 	index int
@@ -73,6 +73,33 @@ func TestSliceSequenceUpdate(t *testing.T) {
 	expect(slice).ToDeepEqual([]interface{}{"Picture", "yourself", "on", "a", "boat", "in", "a", "river"})
 }
 
+func TestSliceSequencePrepend(t *testing.T) {
+	expect := expectFor(t)
+	slice := []interface{}{2, 3}
+	original := NewSliceSequence(slice...)
+	prepended := original.Prepend(1)
+	expect(prepended.ToSlice()).ToDeepEqual([]interface{}{1, 2, 3})
+	expect(original.ToSlice()).ToDeepEqual([]interface{}{2, 3})
+}
+
+func TestSliceSequenceSlice(t *testing.T) {
+	expect := expectFor(t)
+	original := NewSliceSequence(0, 1, 2, 3, 4)
+	slice := original.Slice(1, 4)
+	expect(slice.ToSlice()).ToDeepEqual([]interface{}{1, 2, 3})
+	expect(&slice.(*SliceSequence).slice[0]).ToBe(&original.slice[1])
+	expect(original.Slice(2, 2).Size()).ToBe(0)
+	expect(original.Slice(0, 5).ToSlice()).ToDeepEqual(original.ToSlice())
+
+	// Appending to a slice never writes into the shared slice
+	_ = slice.Append(5)
+	expect(original.ToSlice()).ToDeepEqual([]interface{}{0, 1, 2, 3, 4})
+
+	expect(func() { original.Slice(-1, 2) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { original.Slice(0, 6) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { original.Slice(3, 2) }).ToPanicWith(ErrIndexOutOfRange)
+}
+
 func TestSliceSequenceMap(t *testing.T) {
 	expect := expectFor(t)
 
@@ -169,3 +196,14 @@ func TestSliceSequenceSkipWhile(t *testing.T) {
 
 	expect(seq.SkipWhile(matchFn).ToSlice()).ToDeepEqual([]interface{}{"Ishmael", "Some", "years", "ago"})
 }
+
+func TestSliceSequenceGroupBy(t *testing.T) {
+	expect := expectFor(t)
+	groups := NewSliceSequence(1, 2, 3, 4, 5).GroupBy(func(v interface{}) interface{} {
+		return v.(int) > 2
+	})
+	group, _ := groups.Get(true)
+	expect(group.(Sequence).ToSlice()).ToDeepEqual([]interface{}{3, 4, 5})
+	group, _ = groups.Get(false)
+	expect(group.(Sequence).ToSlice()).ToDeepEqual([]interface{}{1, 2})
+}
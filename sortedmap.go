@@ -0,0 +1,249 @@
+package collections
+
+// A SortedMap is an immutable Map that keeps its keys in the order
+// given by a compareFn, and iterates over its entries in that order.
+// It is implemented as a persistent red-black tree, so lookups and
+// updates are O(log n), and every version of a SortedMap shares most
+// of its structure with the version it was derived from.
+//
+// compareFn must return a negative number, zero or a positive number
+// when its first argument is less than, equal to or greater than its
+// second. Keys that compare equal are the same key.
+type SortedMap struct {
+	compareFn func(interface{}, interface{}) int
+	size      int
+	root      *sortedNode
+}
+
+var _ Map = (*SortedMap)(nil)
+
+// Factory for SortedMaps
+func NewSortedMap(compareFn func(interface{}, interface{}) int) *SortedMap {
+	return &SortedMap{
+		compareFn: compareFn,
+		size:      0,
+		root:      nil,
+	}
+}
+
+// Map Methods
+
+// The number of entries in the map
+func (sortedMap *SortedMap) Size() int {
+	return sortedMap.size
+}
+
+func (sortedMap *SortedMap) Contains(key interface{}) bool {
+	return findSortedNode(sortedMap.root, key, sortedMap.compareFn) != nil
+}
+
+func (sortedMap *SortedMap) Get(key interface{}) (interface{}, bool) {
+	node := findSortedNode(sortedMap.root, key, sortedMap.compareFn)
+	if node == nil {
+		return nil, false
+	}
+	return node.value, true
+}
+
+func (sortedMap *SortedMap) Set(key interface{}, value interface{}) Map {
+	return sortedMap.set(key, value)
+}
+
+func (sortedMap *SortedMap) set(key interface{}, value interface{}) *SortedMap {
+	size := sortedMap.size
+	if existing := findSortedNode(sortedMap.root, key, sortedMap.compareFn); existing == nil {
+		size += 1
+	} else if sameValue(existing.value, value) {
+		return sortedMap
+	}
+	return sortedMap.withRoot(insertSorted(sortedMap.root, key, value, sortedMap.compareFn), size)
+}
+
+func (sortedMap *SortedMap) Remove(key interface{}) Map {
+	return sortedMap.remove(key)
+}
+
+func (sortedMap *SortedMap) remove(key interface{}) *SortedMap {
+	if !sortedMap.Contains(key) {
+		return sortedMap
+	}
+	return sortedMap.withRoot(removeSorted(sortedMap.root, key, sortedMap.compareFn), sortedMap.size-1)
+}
+
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value from other wins.
+func (sortedMap *SortedMap) Merge(other Map) Map {
+	return sortedMap.MergeWith(other, func(key interface{}, left interface{}, right interface{}) interface{} {
+		return right
+	})
+}
+
+// Returns a map with the entries of both maps. Where both maps
+// contain a key, the value is the result of calling resolve with
+// the key, the value in this map and the value in other.
+func (sortedMap *SortedMap) MergeWith(other Map, resolve func(key interface{}, left interface{}, right interface{}) interface{}) Map {
+	return other.Fold(sortedMap, func(state interface{}, item interface{}) interface{} {
+		result, entry := state.(*SortedMap), item.(MapEntry)
+		value := entry.Value
+		if existing, found := result.Get(entry.Key); found {
+			value = resolve(entry.Key, existing, entry.Value)
+		}
+		return result.set(entry.Key, value)
+	}).(*SortedMap)
+}
+
+// Returns a lazy Iterable over the keys of the map, in order
+func (sortedMap *SortedMap) Keys() Iterable {
+	return sortedMap.Map(func(entry interface{}) interface{} {
+		return entry.(MapEntry).Key
+	})
+}
+
+// Returns a lazy Iterable over the values of the map, in the
+// order of their keys
+func (sortedMap *SortedMap) Values() Iterable {
+	return sortedMap.Map(func(entry interface{}) interface{} {
+		return entry.(MapEntry).Value
+	})
+}
+
+// Returns the keys of the map as a SortedSet. This is O(1)
+// because the set shares the tree of the map.
+func (sortedMap *SortedMap) KeySet() Set {
+	return &SortedSet{
+		sortedMap: sortedMap,
+	}
+}
+
+// Ordered Methods
+
+// Returns the entry with the greatest key less than or equal to key,
+// and false if there is no such entry
+func (sortedMap *SortedMap) Floor(key interface{}) (MapEntry, bool) {
+	return entryOf(floorSorted(sortedMap.root, key, sortedMap.compareFn))
+}
+
+// Returns the entry with the least key greater than or equal to key,
+// and false if there is no such entry
+func (sortedMap *SortedMap) Ceiling(key interface{}) (MapEntry, bool) {
+	return entryOf(ceilingSorted(sortedMap.root, key, sortedMap.compareFn))
+}
+
+// Returns the entry with the least key, and false if the map is empty
+func (sortedMap *SortedMap) First() (MapEntry, bool) {
+	return entryOf(firstSorted(sortedMap.root))
+}
+
+// Returns the entry with the greatest key, and false if the map is empty
+func (sortedMap *SortedMap) Last() (MapEntry, bool) {
+	return entryOf(lastSorted(sortedMap.root))
+}
+
+// Returns a lazy Iterable over the entries whose keys are at least
+// from and less than to, in order. Like other Streams, the result
+// can only be iterated once.
+func (sortedMap *SortedMap) Range(from interface{}, to interface{}) Iterable {
+	return NewStream(&SortedMapIterator{
+		tree: newSortedRangeIterator(sortedMap.root, from, to, false, sortedMap.compareFn),
+	})
+}
+
+func entryOf(node *sortedNode) (MapEntry, bool) {
+	if node == nil {
+		return MapEntry{}, false
+	}
+	return MapEntry{
+		Key:   node.key,
+		Value: node.value,
+	}, true
+}
+
+func (sortedMap *SortedMap) withRoot(root *sortedNode, size int) *SortedMap {
+	return &SortedMap{
+		compareFn: sortedMap.compareFn,
+		size:      size,
+		root:      root,
+	}
+}
+
+// Iterable Methods
+
+func (sortedMap *SortedMap) Iterator() Iterator {
+	return &SortedMapIterator{
+		tree: newSortedTreeIterator(sortedMap.root),
+	}
+}
+
+func (sortedMap *SortedMap) ForEach(iterFn func(interface{})) {
+	forEachHelper(sortedMap, iterFn)
+}
+
+func (sortedMap *SortedMap) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(sortedMap, mapFn)
+}
+
+func (sortedMap *SortedMap) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(sortedMap, filterFn)
+}
+
+func (sortedMap *SortedMap) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(sortedMap, initialValue, reducerFn)
+}
+
+func (sortedMap *SortedMap) ToSlice() []interface{} {
+	return toSliceHelper(sortedMap)
+}
+
+func (sortedMap *SortedMap) ToVector() *Vector {
+	return toVectorHelper(sortedMap)
+}
+
+func (sortedMap *SortedMap) Take(count int) Iterable {
+	return takeHelper(sortedMap, count)
+}
+
+func (sortedMap *SortedMap) Skip(count int) Iterable {
+	return skipHelper(sortedMap, count)
+}
+
+func (sortedMap *SortedMap) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(sortedMap, matchFn)
+}
+
+func (sortedMap *SortedMap) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(sortedMap, groupFn)
+}
+
+func (sortedMap *SortedMap) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(sortedMap, groupFn, initialValue, reducerFn)
+}
+
+func (sortedMap *SortedMap) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(sortedMap, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Map with the same keys and values
+func (sortedMap *SortedMap) Equals(other interface{}) bool {
+	return equalMaps(sortedMap, other)
+}
+
+func (sortedMap *SortedMap) Hash() uint64 {
+	return hashOfMap(sortedMap)
+}
+
+// An Iterator over the entries of a SortedMap in key order.
+// Yields MapEntry values.
+type SortedMapIterator struct {
+	tree *sortedTreeIterator
+}
+
+func (iterator *SortedMapIterator) MoveNext() bool {
+	return iterator.tree.MoveNext()
+}
+
+func (iterator *SortedMapIterator) Current() interface{} {
+	entry, _ := entryOf(iterator.tree.currentNode())
+	return entry
+}
@@ -0,0 +1,208 @@
+package collections
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func compareInts(a interface{}, b interface{}) int {
+	return a.(int) - b.(int)
+}
+
+// Checks the red-black invariants of the tree: keys are in order,
+// the root is black, no red node has a red child, every path from
+// the root to a leaf has the same number of black nodes, and cached
+// sizes are up to date
+func expectRedBlackTree(t *testing.T, root *sortedNode, compareFn func(interface{}, interface{}) int) {
+	t.Helper()
+	if isRed(root) {
+		t.Fatalf("expected a black root")
+	}
+	var check func(node *sortedNode, lower *sortedNode, upper *sortedNode) int
+	check = func(node *sortedNode, lower *sortedNode, upper *sortedNode) int {
+		if node == nil {
+			return 1
+		}
+		if lower != nil && compareFn(lower.key, node.key) >= 0 || upper != nil && compareFn(node.key, upper.key) >= 0 {
+			t.Fatalf("key %v is out of order", node.key)
+		}
+		if node.red && (isRed(node.left) || isRed(node.right)) {
+			t.Fatalf("red node %v has a red child", node.key)
+		}
+		if node.size != sortedSize(node.left)+1+sortedSize(node.right) {
+			t.Fatalf("node %v has a stale size %d", node.key, node.size)
+		}
+		leftHeight := check(node.left, lower, node)
+		rightHeight := check(node.right, node, upper)
+		if leftHeight != rightHeight {
+			t.Fatalf("node %v has black heights %d and %d", node.key, leftHeight, rightHeight)
+		}
+		if node.red {
+			return leftHeight
+		}
+		return leftHeight + 1
+	}
+	check(root, nil, nil)
+}
+
+func TestSortedMapIsAMap(t *testing.T) {
+	expect := expectFor(t)
+	expect(NewSortedMap(compareInts)).ToBeAssignableTo(reflect.TypeOf((*Map)(nil)).Elem())
+}
+
+func TestSortedMapSetAndGet(t *testing.T) {
+	expect := expectFor(t)
+	m0 := NewSortedMap(compareInts)
+	m1 := m0.Set(3, "three").Set(1, "one").Set(2, "two")
+	val, found := m1.Get(2)
+	expect(val).ToBe("two")
+	expect(found).ToBe(true)
+	_, found = m0.Get(2)
+	expect(found).ToBe(false)
+	expect(m1.(*SortedMap).Size()).ToBe(3)
+
+	m2 := m1.Set(2, "deux")
+	val, _ = m2.Get(2)
+	expect(val).ToBe("deux")
+	val, _ = m1.Get(2)
+	expect(val).ToBe("two")
+	expect(m2.(*SortedMap).Size()).ToBe(3)
+	expect(m2.Set(2, "deux")).ToBe(m2)
+}
+
+func TestSortedMapIteratesInOrder(t *testing.T) {
+	expect := expectFor(t)
+	m := NewSortedMap(func(a interface{}, b interface{}) int {
+		return strings.Compare(a.(string), b.(string))
+	})
+	for _, key := range []string{"pear", "apple", "fig", "banana", "cherry"} {
+		m = m.set(key, len(key))
+	}
+	expect(m.Keys().ToSlice()).ToDeepEqual([]interface{}{"apple", "banana", "cherry", "fig", "pear"})
+	expect(m.Values().ToSlice()).ToDeepEqual([]interface{}{5, 6, 6, 3, 4})
+	expect(NewSortedMap(compareInts).ToSlice()).ToDeepEqual([]interface{}{})
+}
+
+func TestSortedMapMatchesModel(t *testing.T) {
+	random := fakerFor(t)
+	expect := expectFor(t)
+	m := NewSortedMap(compareInts)
+	model := map[int]int{}
+	for i := 0; i < 5000; i++ {
+		key := random.rand.Intn(400)
+		if random.rand.Intn(2) == 0 {
+			m = m.remove(key)
+			delete(model, key)
+		} else {
+			m = m.set(key, i)
+			model[key] = i
+		}
+		if i%50 == 0 {
+			expectRedBlackTree(t, m.root, compareInts)
+		}
+	}
+	expectRedBlackTree(t, m.root, compareInts)
+	keys := []int{}
+	for key := range model {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	expected := []interface{}{}
+	for _, key := range keys {
+		expected = append(expected, MapEntry{Key: key, Value: model[key]})
+	}
+	expect(m.Size()).ToBe(len(model))
+	expect(m.ToSlice()).ToDeepEqual(expected)
+}
+
+func TestSortedMapRemove(t *testing.T) {
+	expect := expectFor(t)
+	m := NewSortedMap(compareInts)
+	for i := 0; i < 1000; i++ {
+		m = m.set(i, i)
+	}
+	expect(m.Remove(5000)).ToBe(m)
+	for i := 0; i < 1000; i++ {
+		m = m.remove(i)
+		expectRedBlackTree(t, m.root, compareInts)
+		expect(m.Contains(i)).ToBe(false)
+	}
+	expect(m.Size()).ToBe(0)
+	expect(m.root == nil).ToBe(true)
+}
+
+func TestSortedMapFloorAndCeiling(t *testing.T) {
+	expect := expectFor(t)
+	m := NewSortedMap(compareInts).Set(10, "a").Set(20, "b").Set(30, "c").(*SortedMap)
+
+	entry, found := m.Floor(25)
+	expect(entry).ToBe(MapEntry{Key: 20, Value: "b"})
+	expect(found).ToBe(true)
+	entry, _ = m.Floor(20)
+	expect(entry.Key).ToBe(20)
+	_, found = m.Floor(5)
+	expect(found).ToBe(false)
+
+	entry, found = m.Ceiling(25)
+	expect(entry).ToBe(MapEntry{Key: 30, Value: "c"})
+	expect(found).ToBe(true)
+	entry, _ = m.Ceiling(10)
+	expect(entry.Key).ToBe(10)
+	_, found = m.Ceiling(35)
+	expect(found).ToBe(false)
+
+	entry, _ = m.First()
+	expect(entry.Key).ToBe(10)
+	entry, _ = m.Last()
+	expect(entry.Key).ToBe(30)
+	_, found = NewSortedMap(compareInts).First()
+	expect(found).ToBe(false)
+	_, found = NewSortedMap(compareInts).Last()
+	expect(found).ToBe(false)
+}
+
+func TestSortedMapRange(t *testing.T) {
+	expect := expectFor(t)
+	m := NewSortedMap(compareInts)
+	for i := 0; i < 100; i += 10 {
+		m = m.set(i, i)
+	}
+	keys := func(iterable Iterable) []interface{} {
+		return iterable.Map(func(entry interface{}) interface{} { return entry.(MapEntry).Key }).ToSlice()
+	}
+	expect(keys(m.Range(20, 50))).ToDeepEqual([]interface{}{20, 30, 40})
+	expect(keys(m.Range(15, 51))).ToDeepEqual([]interface{}{20, 30, 40, 50})
+	expect(keys(m.Range(-5, 5))).ToDeepEqual([]interface{}{0})
+	expect(keys(m.Range(95, 200))).ToDeepEqual([]interface{}{})
+	expect(keys(m.Range(50, 50))).ToDeepEqual([]interface{}{})
+
+	// Range is lazy, so taking a few entries doesn't walk the rest
+	visited := 0
+	m.Range(0, 100).Map(func(entry interface{}) interface{} {
+		visited++
+		return entry
+	}).Take(2).ToSlice()
+	expect(visited).ToBe(2)
+}
+
+func TestSortedMapMerge(t *testing.T) {
+	expect := expectFor(t)
+	left := NewSortedMap(compareInts).Set(1, 1).Set(2, 2)
+	right := NewHashMap().Set(2, 20).Set(3, 30)
+	expect(left.Merge(right).Values().ToSlice()).ToDeepEqual([]interface{}{1, 20, 30})
+	expect(left.MergeWith(right, sumCounts).Values().ToSlice()).ToDeepEqual([]interface{}{1, 22, 30})
+	expect(left.KeySet().Contains(2)).ToBe(true)
+	expect(left.KeySet().Size()).ToBe(2)
+}
+
+func TestSortedMapIteratorBounds(t *testing.T) {
+	expect := expectFor(t)
+	iterator := NewSortedMap(compareInts).Set(1, 1).Iterator()
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+	expect(iterator.MoveNext()).ToBe(true)
+	expect(iterator.Current()).ToBe(MapEntry{Key: 1, Value: 1})
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+}
@@ -0,0 +1,211 @@
+package collections
+
+// A SortedSet is an immutable Set that keeps its elements in the
+// order given by a compareFn, and iterates over them in that order.
+// The elements of the set are the keys of an underlying SortedMap,
+// so updates are O(log n). Every node of the tree knows the size of
+// its subtree, which makes Rank and Select O(log n) as well.
+//
+// See SortedMap for the contract of compareFn.
+type SortedSet struct {
+	sortedMap *SortedMap
+}
+
+var _ Set = (*SortedSet)(nil)
+
+// Factory for SortedSets
+func NewSortedSet(compareFn func(interface{}, interface{}) int, values ...interface{}) *SortedSet {
+	sortedMap := NewSortedMap(compareFn)
+	for _, value := range values {
+		sortedMap = sortedMap.set(value, nil)
+	}
+	return &SortedSet{
+		sortedMap: sortedMap,
+	}
+}
+
+// Set Methods
+
+func (set *SortedSet) Size() int {
+	return set.sortedMap.size
+}
+
+func (set *SortedSet) Contains(value interface{}) bool {
+	return set.sortedMap.Contains(value)
+}
+
+func (set *SortedSet) SubsetOf(other Set) bool {
+	if set.Size() > other.Size() {
+		return false
+	}
+	return !set.Any(func(value interface{}) bool {
+		return !other.Contains(value)
+	})
+}
+
+func (set *SortedSet) Add(value interface{}) Set {
+	if set.Contains(value) {
+		return set
+	}
+	return set.withMap(set.sortedMap.set(value, nil))
+}
+
+func (set *SortedSet) Remove(value interface{}) Set {
+	return set.withMap(set.sortedMap.remove(value))
+}
+
+func (set *SortedSet) Intersect(other Set) Set {
+	result := set.sortedMap
+	set.ForEach(func(value interface{}) {
+		if !other.Contains(value) {
+			result = result.remove(value)
+		}
+	})
+	return set.withMap(result)
+}
+
+func (set *SortedSet) Union(other Set) Set {
+	result := set.sortedMap
+	other.ForEach(func(value interface{}) {
+		if !result.Contains(value) {
+			result = result.set(value, nil)
+		}
+	})
+	return set.withMap(result)
+}
+
+func (set *SortedSet) Difference(other Set) Set {
+	result := set.sortedMap
+	other.ForEach(func(value interface{}) {
+		result = result.remove(value)
+	})
+	return set.withMap(result)
+}
+
+func (set *SortedSet) withMap(sortedMap *SortedMap) *SortedSet {
+	if sortedMap == set.sortedMap {
+		return set
+	}
+	return &SortedSet{
+		sortedMap: sortedMap,
+	}
+}
+
+// Ordered Methods
+
+// Returns the least element, and false if the set is empty
+func (set *SortedSet) Min() (interface{}, bool) {
+	entry, found := set.sortedMap.First()
+	return entry.Key, found
+}
+
+// Returns the greatest element, and false if the set is empty
+func (set *SortedSet) Max() (interface{}, bool) {
+	entry, found := set.sortedMap.Last()
+	return entry.Key, found
+}
+
+// Returns the number of elements less than value. If value is in
+// the set, this is its index in iteration order.
+func (set *SortedSet) Rank(value interface{}) int {
+	return rankSorted(set.sortedMap.root, value, set.sortedMap.compareFn)
+}
+
+// Returns the element at the index in iteration order, so that
+// Select(Rank(v)) == v for every element v. Panics with
+// ErrIndexOutOfRange if the index is out of range.
+func (set *SortedSet) Select(index int) interface{} {
+	if index < 0 || index >= set.Size() {
+		panic(ErrIndexOutOfRange)
+	}
+	return selectSorted(set.sortedMap.root, index).key
+}
+
+// Returns a lazy Iterable over the elements that are at least lo
+// and at most hi, in order. Like other Streams, the result can only
+// be iterated once.
+func (set *SortedSet) Between(lo interface{}, hi interface{}) Iterable {
+	return NewStream(&SortedSetIterator{
+		tree: newSortedRangeIterator(set.sortedMap.root, lo, hi, true, set.sortedMap.compareFn),
+	})
+}
+
+// Iterable Methods
+
+func (set *SortedSet) Iterator() Iterator {
+	return &SortedSetIterator{
+		tree: newSortedTreeIterator(set.sortedMap.root),
+	}
+}
+
+func (set *SortedSet) ForEach(iterFn func(interface{})) {
+	forEachHelper(set, iterFn)
+}
+
+func (set *SortedSet) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(set, mapFn)
+}
+
+func (set *SortedSet) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(set, filterFn)
+}
+
+func (set *SortedSet) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(set, initialValue, reducerFn)
+}
+
+func (set *SortedSet) ToSlice() []interface{} {
+	return toSliceHelper(set)
+}
+
+func (set *SortedSet) ToVector() *Vector {
+	return toVectorHelper(set)
+}
+
+func (set *SortedSet) Take(count int) Iterable {
+	return takeHelper(set, count)
+}
+
+func (set *SortedSet) Skip(count int) Iterable {
+	return skipHelper(set, count)
+}
+
+func (set *SortedSet) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(set, matchFn)
+}
+
+func (set *SortedSet) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(set, groupFn)
+}
+
+func (set *SortedSet) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(set, groupFn, initialValue, reducerFn)
+}
+
+func (set *SortedSet) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(set, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Set with the same elements
+func (set *SortedSet) Equals(other interface{}) bool {
+	return equalSets(set, other)
+}
+
+func (set *SortedSet) Hash() uint64 {
+	return hashOfSet(set)
+}
+
+// An Iterator over the elements of a SortedSet in order
+type SortedSetIterator struct {
+	tree *sortedTreeIterator
+}
+
+func (iterator *SortedSetIterator) MoveNext() bool {
+	return iterator.tree.MoveNext()
+}
+
+func (iterator *SortedSetIterator) Current() interface{} {
+	return iterator.tree.currentNode().key
+}
@@ -0,0 +1,103 @@
+package collections
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSortedSetIsASet(t *testing.T) {
+	expect := expectFor(t)
+	expect(NewSortedSet(compareInts)).ToBeAssignableTo(reflect.TypeOf((*Set)(nil)).Elem())
+}
+
+func TestSortedSetIteratesInOrder(t *testing.T) {
+	expect := expectFor(t)
+	set := NewSortedSet(compareInts, 5, 3, 9, 1, 3)
+	expect(set.Size()).ToBe(4)
+	expect(set.ToSlice()).ToDeepEqual([]interface{}{1, 3, 5, 9})
+	expect(set.Add(4).ToSlice()).ToDeepEqual([]interface{}{1, 3, 4, 5, 9})
+	expect(set.Remove(3).ToSlice()).ToDeepEqual([]interface{}{1, 5, 9})
+	expect(set.Add(3)).ToBe(set)
+	expect(set.Remove(7)).ToBe(set)
+}
+
+func TestSortedSetOperations(t *testing.T) {
+	expect := expectFor(t)
+	a := NewSortedSet(compareInts, 1, 2, 3, 4)
+	b := NewHashSet(3, 4, 5)
+	expect(a.Union(b).ToSlice()).ToDeepEqual([]interface{}{1, 2, 3, 4, 5})
+	expect(a.Intersect(b).ToSlice()).ToDeepEqual([]interface{}{3, 4})
+	expect(a.Difference(b).ToSlice()).ToDeepEqual([]interface{}{1, 2})
+	expect(a.SubsetOf(b)).ToBe(false)
+	expect(NewSortedSet(compareInts, 3, 4).SubsetOf(a)).ToBe(true)
+	expect(a.Intersect(a)).ToBe(a)
+}
+
+func TestSortedSetMinAndMax(t *testing.T) {
+	expect := expectFor(t)
+	set := NewSortedSet(compareInts, 5, 3, 9)
+	min, found := set.Min()
+	expect(min).ToBe(3)
+	expect(found).ToBe(true)
+	max, found := set.Max()
+	expect(max).ToBe(9)
+	expect(found).ToBe(true)
+	_, found = NewSortedSet(compareInts).Min()
+	expect(found).ToBe(false)
+	_, found = NewSortedSet(compareInts).Max()
+	expect(found).ToBe(false)
+}
+
+func TestSortedSetRankAndSelect(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	set := NewSortedSet(compareInts)
+	model := map[int]bool{}
+	for i := 0; i < 2000; i++ {
+		value := random.rand.Intn(1000)
+		if random.rand.Intn(3) == 0 {
+			set = set.Remove(value).(*SortedSet)
+			delete(model, value)
+		} else {
+			set = set.Add(value).(*SortedSet)
+			model[value] = true
+		}
+	}
+	expectRedBlackTree(t, set.sortedMap.root, compareInts)
+	values := []int{}
+	for value := range model {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+	for index, value := range values {
+		expect(set.Rank(value)).ToBe(index)
+		expect(set.Select(index)).ToBe(value)
+	}
+	// Values not in the set rank where they would be inserted
+	expect(set.Rank(-1)).ToBe(0)
+	expect(set.Rank(1000)).ToBe(len(values))
+	expect(set.Rank(values[0] + 1)).ToBe(sort.SearchInts(values, values[0]+1))
+
+	expect(func() { set.Select(-1) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { set.Select(len(values)) }).ToPanicWith(ErrIndexOutOfRange)
+}
+
+func TestSortedSetBetween(t *testing.T) {
+	expect := expectFor(t)
+	set := NewSortedSet(compareInts, 10, 20, 30, 40, 50)
+	expect(set.Between(20, 40).ToSlice()).ToDeepEqual([]interface{}{20, 30, 40})
+	expect(set.Between(15, 45).ToSlice()).ToDeepEqual([]interface{}{20, 30, 40})
+	expect(set.Between(30, 30).ToSlice()).ToDeepEqual([]interface{}{30})
+	expect(set.Between(31, 39).ToSlice()).ToDeepEqual([]interface{}{})
+	expect(set.Between(0, 100).ToSlice()).ToDeepEqual([]interface{}{10, 20, 30, 40, 50})
+	expect(set.Between(0, 100).Take(2).ToSlice()).ToDeepEqual([]interface{}{10, 20})
+}
+
+func TestSortedMapKeySet(t *testing.T) {
+	expect := expectFor(t)
+	m := NewSortedMap(compareInts).Set(2, "b").Set(1, "a")
+	keySet := m.KeySet().(*SortedSet)
+	expect(keySet.ToSlice()).ToDeepEqual([]interface{}{1, 2})
+	expect(keySet.sortedMap).ToBe(m)
+}
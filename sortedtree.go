@@ -0,0 +1,373 @@
+package collections
+
+// A persistent red-black tree, the structure behind SortedMap.
+//
+// Insertion follows Okasaki, and deletion follows Kahrs ("Red-black
+// trees with types", JFP 2001). Both copy only the path from the root
+// to the changed node, and never modify an existing node, so every
+// version of a tree shares most of its nodes with the version it was
+// derived from. A nil *sortedNode is an empty (black) tree.
+//
+// The tree is ordered by a compareFn, which returns a negative number,
+// zero or a positive number when its first argument is less than, equal
+// to or greater than its second.
+//
+// Each node caches the number of nodes in its subtree, which gives
+// O(log n) rank and select.
+type sortedNode struct {
+	red   bool
+	size  int
+	left  *sortedNode
+	right *sortedNode
+	key   interface{}
+	value interface{}
+}
+
+// Returns a new node with the key and value of entry
+func newSortedNode(red bool, left *sortedNode, entry *sortedNode, right *sortedNode) *sortedNode {
+	return &sortedNode{
+		red:   red,
+		size:  sortedSize(left) + 1 + sortedSize(right),
+		left:  left,
+		right: right,
+		key:   entry.key,
+		value: entry.value,
+	}
+}
+
+func sortedSize(node *sortedNode) int {
+	if node == nil {
+		return 0
+	}
+	return node.size
+}
+
+func isRed(node *sortedNode) bool {
+	return node != nil && node.red
+}
+
+// Black and not empty
+func isBlack(node *sortedNode) bool {
+	return node != nil && !node.red
+}
+
+// Returns the node for the key, or nil
+func findSortedNode(node *sortedNode, key interface{}, compareFn func(interface{}, interface{}) int) *sortedNode {
+	for node != nil {
+		comparison := compareFn(key, node.key)
+		if comparison < 0 {
+			node = node.left
+		} else if comparison > 0 {
+			node = node.right
+		} else {
+			return node
+		}
+	}
+	return nil
+}
+
+// Returns a tree with the key set to the value. The key
+// is added if it is not in the tree.
+func insertSorted(root *sortedNode, key interface{}, value interface{}, compareFn func(interface{}, interface{}) int) *sortedNode {
+	var insert func(node *sortedNode) *sortedNode
+	insert = func(node *sortedNode) *sortedNode {
+		if node == nil {
+			return &sortedNode{red: true, size: 1, key: key, value: value}
+		}
+		comparison := compareFn(key, node.key)
+		switch {
+		case comparison < 0 && node.red:
+			return newSortedNode(true, insert(node.left), node, node.right)
+		case comparison < 0:
+			return balanceSorted(insert(node.left), node, node.right)
+		case comparison > 0 && node.red:
+			return newSortedNode(true, node.left, node, insert(node.right))
+		case comparison > 0:
+			return balanceSorted(node.left, node, insert(node.right))
+		default:
+			return &sortedNode{red: node.red, size: node.size, left: node.left, right: node.right, key: node.key, value: value}
+		}
+	}
+	return blacken(insert(root))
+}
+
+// Returns a tree without the key, which must be in the tree
+func removeSorted(root *sortedNode, key interface{}, compareFn func(interface{}, interface{}) int) *sortedNode {
+	var remove func(node *sortedNode) *sortedNode
+	remove = func(node *sortedNode) *sortedNode {
+		comparison := compareFn(key, node.key)
+		switch {
+		case comparison < 0 && isBlack(node.left):
+			return balanceLeft(remove(node.left), node, node.right)
+		case comparison < 0:
+			return newSortedNode(true, remove(node.left), node, node.right)
+		case comparison > 0 && isBlack(node.right):
+			return balanceRight(node.left, node, remove(node.right))
+		case comparison > 0:
+			return newSortedNode(true, node.left, node, remove(node.right))
+		default:
+			return appendSorted(node.left, node.right)
+		}
+	}
+	return blacken(remove(root))
+}
+
+func blacken(node *sortedNode) *sortedNode {
+	if !isRed(node) {
+		return node
+	}
+	return newSortedNode(false, node.left, node, node.right)
+}
+
+// Returns a tree equivalent to a black node with the given children,
+// fixing a red child with a red child of its own
+func balanceSorted(left *sortedNode, entry *sortedNode, right *sortedNode) *sortedNode {
+	switch {
+	case isRed(left) && isRed(right):
+		return newSortedNode(true, blacken(left), entry, blacken(right))
+	case isRed(left) && isRed(left.left):
+		return newSortedNode(true, blacken(left.left), left, newSortedNode(false, left.right, entry, right))
+	case isRed(left) && isRed(left.right):
+		return newSortedNode(true,
+			newSortedNode(false, left.left, left, left.right.left),
+			left.right,
+			newSortedNode(false, left.right.right, entry, right))
+	case isRed(right) && isRed(right.right):
+		return newSortedNode(true, newSortedNode(false, left, entry, right.left), right, blacken(right.right))
+	case isRed(right) && isRed(right.left):
+		return newSortedNode(true,
+			newSortedNode(false, left, entry, right.left.left),
+			right.left,
+			newSortedNode(false, right.left.right, right, right.right))
+	default:
+		return newSortedNode(false, left, entry, right)
+	}
+}
+
+// Rebalances a node whose left subtree has lost one black node
+func balanceLeft(left *sortedNode, entry *sortedNode, right *sortedNode) *sortedNode {
+	switch {
+	case isRed(left):
+		return newSortedNode(true, blacken(left), entry, right)
+	case isBlack(right):
+		return balanceSorted(left, entry, redden(right))
+	case isRed(right) && isBlack(right.left):
+		return newSortedNode(true,
+			newSortedNode(false, left, entry, right.left.left),
+			right.left,
+			balanceSorted(right.left.right, right, redden(right.right)))
+	default:
+		panic(ErrImpossible)
+	}
+}
+
+// Rebalances a node whose right subtree has lost one black node
+func balanceRight(left *sortedNode, entry *sortedNode, right *sortedNode) *sortedNode {
+	switch {
+	case isRed(right):
+		return newSortedNode(true, left, entry, blacken(right))
+	case isBlack(left):
+		return balanceSorted(redden(left), entry, right)
+	case isRed(left) && isBlack(left.right):
+		return newSortedNode(true,
+			balanceSorted(redden(left.left), left, left.right.left),
+			left.right,
+			newSortedNode(false, left.right.right, entry, right))
+	default:
+		panic(ErrImpossible)
+	}
+}
+
+// Turns a black node red, lowering its black height by one
+func redden(node *sortedNode) *sortedNode {
+	if !isBlack(node) {
+		panic(ErrImpossible)
+	}
+	return newSortedNode(true, node.left, node, node.right)
+}
+
+// Joins two trees of the same black height, where every key
+// of left is less than every key of right
+func appendSorted(left *sortedNode, right *sortedNode) *sortedNode {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case left.red && right.red:
+		middle := appendSorted(left.right, right.left)
+		if isRed(middle) {
+			return newSortedNode(true,
+				newSortedNode(true, left.left, left, middle.left),
+				middle,
+				newSortedNode(true, middle.right, right, right.right))
+		}
+		return newSortedNode(true, left.left, left, newSortedNode(true, middle, right, right.right))
+	case !left.red && !right.red:
+		middle := appendSorted(left.right, right.left)
+		if isRed(middle) {
+			return newSortedNode(true,
+				newSortedNode(false, left.left, left, middle.left),
+				middle,
+				newSortedNode(false, middle.right, right, right.right))
+		}
+		return balanceLeft(left.left, left, newSortedNode(false, middle, right, right.right))
+	case right.red:
+		return newSortedNode(true, appendSorted(left, right.left), right, right.right)
+	default:
+		return newSortedNode(true, left.left, left, appendSorted(left.right, right))
+	}
+}
+
+// Returns the node with the greatest key less than or equal to
+// key, or nil if there isn't one
+func floorSorted(node *sortedNode, key interface{}, compareFn func(interface{}, interface{}) int) *sortedNode {
+	var result *sortedNode
+	for node != nil {
+		comparison := compareFn(key, node.key)
+		if comparison < 0 {
+			node = node.left
+		} else if comparison > 0 {
+			result = node
+			node = node.right
+		} else {
+			return node
+		}
+	}
+	return result
+}
+
+// Returns the node with the least key greater than or equal to
+// key, or nil if there isn't one
+func ceilingSorted(node *sortedNode, key interface{}, compareFn func(interface{}, interface{}) int) *sortedNode {
+	var result *sortedNode
+	for node != nil {
+		comparison := compareFn(key, node.key)
+		if comparison < 0 {
+			result = node
+			node = node.left
+		} else if comparison > 0 {
+			node = node.right
+		} else {
+			return node
+		}
+	}
+	return result
+}
+
+// Returns the number of keys in the tree less than key
+func rankSorted(node *sortedNode, key interface{}, compareFn func(interface{}, interface{}) int) int {
+	rank := 0
+	for node != nil {
+		if compareFn(key, node.key) <= 0 {
+			node = node.left
+		} else {
+			rank += sortedSize(node.left) + 1
+			node = node.right
+		}
+	}
+	return rank
+}
+
+// Returns the node with the given rank, i.e. with index keys
+// less than it. The index must be in range.
+func selectSorted(node *sortedNode, index int) *sortedNode {
+	for {
+		leftSize := sortedSize(node.left)
+		if index < leftSize {
+			node = node.left
+		} else if index > leftSize {
+			index -= leftSize + 1
+			node = node.right
+		} else {
+			return node
+		}
+	}
+}
+
+func firstSorted(node *sortedNode) *sortedNode {
+	for node != nil && node.left != nil {
+		node = node.left
+	}
+	return node
+}
+
+func lastSorted(node *sortedNode) *sortedNode {
+	for node != nil && node.right != nil {
+		node = node.right
+	}
+	return node
+}
+
+// An in-order iterator over the nodes of a tree, optionally
+// stopping at an upper bound
+type sortedTreeIterator struct {
+	stack          []*sortedNode
+	current        *sortedNode
+	compareFn      func(interface{}, interface{}) int
+	upper          interface{}
+	hasUpper       bool
+	upperInclusive bool
+}
+
+// Returns an iterator over every node of the tree
+func newSortedTreeIterator(root *sortedNode) *sortedTreeIterator {
+	iterator := &sortedTreeIterator{}
+	iterator.pushLeftPath(root)
+	return iterator
+}
+
+// Returns an iterator over the nodes whose keys are at least
+// lower and less than upper, or at most upper if upperInclusive
+func newSortedRangeIterator(root *sortedNode, lower interface{}, upper interface{}, upperInclusive bool, compareFn func(interface{}, interface{}) int) *sortedTreeIterator {
+	iterator := &sortedTreeIterator{
+		compareFn:      compareFn,
+		upper:          upper,
+		hasUpper:       true,
+		upperInclusive: upperInclusive,
+	}
+	for node := root; node != nil; {
+		if compareFn(node.key, lower) >= 0 {
+			iterator.stack = append(iterator.stack, node)
+			node = node.left
+		} else {
+			node = node.right
+		}
+	}
+	return iterator
+}
+
+func (iterator *sortedTreeIterator) pushLeftPath(node *sortedNode) {
+	for ; node != nil; node = node.left {
+		iterator.stack = append(iterator.stack, node)
+	}
+}
+
+func (iterator *sortedTreeIterator) MoveNext() bool {
+	if len(iterator.stack) == 0 {
+		iterator.current = nil
+		return false
+	}
+	node := iterator.stack[len(iterator.stack)-1]
+	iterator.stack = iterator.stack[:len(iterator.stack)-1]
+	if iterator.hasUpper && iterator.pastUpper(node) {
+		iterator.stack = nil
+		iterator.current = nil
+		return false
+	}
+	iterator.pushLeftPath(node.right)
+	iterator.current = node
+	return true
+}
+
+func (iterator *sortedTreeIterator) pastUpper(node *sortedNode) bool {
+	comparison := iterator.compareFn(node.key, iterator.upper)
+	return comparison > 0 || comparison == 0 && !iterator.upperInclusive
+}
+
+func (iterator *sortedTreeIterator) currentNode() *sortedNode {
+	if iterator.current == nil {
+		panic(ErrIterationOutOfRange)
+	}
+	return iterator.current
+}
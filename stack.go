@@ -79,6 +79,17 @@ func (stack *EmptyStack) Append(item interface{}) Sequence {
 	return stack.Push(item)
 }
 
+func (stack *EmptyStack) Prepend(item interface{}) Sequence {
+	return stack.Push(item)
+}
+
+func (stack *EmptyStack) Slice(start int, end int) Sequence {
+	if start != 0 || end != 0 {
+		panic(ErrIndexOutOfRange)
+	}
+	return stack
+}
+
 func (stack *EmptyStack) Get(index int) interface{} {
 	panic(ErrIndexOutOfRange)
 }
@@ -111,6 +122,10 @@ func (iterable *EmptyStack) ToSlice() []interface{} {
 	return []interface{}{}
 }
 
+func (iterable *EmptyStack) ToVector() *Vector {
+	return NewVector()
+}
+
 func (iterable *EmptyStack) Take(count int) Iterable {
 	return iterable
 }
@@ -123,10 +138,29 @@ func (iterable *EmptyStack) SkipWhile(matchFn func(interface{}) bool) Iterable {
 	return iterable
 }
 
+func (iterable *EmptyStack) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return NewHashMap()
+}
+
+func (iterable *EmptyStack) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return NewHashMap()
+}
+
 func (iterable *EmptyStack) Any(matchFn func(interface{}) bool) bool {
 	return false
 }
 
+// Hashable Methods
+
+// Returns true if other is a Sequence with the same elements in the same order
+func (iterable *EmptyStack) Equals(other interface{}) bool {
+	return equalSequences(iterable, other)
+}
+
+func (iterable *EmptyStack) Hash() uint64 {
+	return hashOfSequence(iterable)
+}
+
 type NonEmptyStack struct {
 	size int
 	head interface{}
@@ -177,6 +211,32 @@ func (stack *NonEmptyStack) Append(item interface{}) Sequence {
 	return copy
 }
 
+// Prepending to a stack is an O(1) Push
+func (stack *NonEmptyStack) Prepend(item interface{}) Sequence {
+	return stack.Push(item)
+}
+
+// Slices in O(end). A slice that runs to the end of the stack shares
+// its nodes, while any other slice copies the elements it keeps.
+func (stack *NonEmptyStack) Slice(start int, end int) Sequence {
+	if start < 0 || end > stack.size || start > end {
+		panic(ErrIndexOutOfRange)
+	}
+	var current Stack = stack
+	for i := 0; i < start; i++ {
+		current = current.(*NonEmptyStack).tail
+	}
+	if end == stack.size {
+		return current
+	}
+	items := current.Take(end - start).ToSlice()
+	var result Stack = NewStack()
+	for i := len(items) - 1; i >= 0; i-- {
+		result = result.Push(items[i])
+	}
+	return result
+}
+
 func (stack *NonEmptyStack) Get(index int) interface{} {
 	if index == 0 {
 		return stack.head
@@ -251,6 +311,10 @@ func (stack *NonEmptyStack) ToSlice() []interface{} {
 	return toSliceHelper(stack)
 }
 
+func (stack *NonEmptyStack) ToVector() *Vector {
+	return toVectorHelper(stack)
+}
+
 func (stack *NonEmptyStack) Take(count int) Iterable {
 	return takeHelper(stack, count)
 }
@@ -263,10 +327,29 @@ func (stack *NonEmptyStack) SkipWhile(matchFn func(interface{}) bool) Iterable {
 	return skipWhileHelper(stack, matchFn)
 }
 
+func (stack *NonEmptyStack) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(stack, groupFn)
+}
+
+func (stack *NonEmptyStack) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(stack, groupFn, initialValue, reducerFn)
+}
+
 func (stack *NonEmptyStack) Any(matchFn func(interface{}) bool) bool {
 	return anyHelper(stack, matchFn)
 }
 
+// Hashable Methods
+
+// Returns true if other is a Sequence with the same elements in the same order
+func (stack *NonEmptyStack) Equals(other interface{}) bool {
+	return equalSequences(stack, other)
+}
+
+func (stack *NonEmptyStack) Hash() uint64 {
+	return hashOfSequence(stack)
+}
+
 func (stack *NonEmptyStack) String() string {
 	return fmt.Sprintf("%v::%v", stack.head, stack.tail.String())
 }
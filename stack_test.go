@@ -128,3 +128,57 @@ func TestStackUpdate(t *testing.T) {
 	expect(updated.ToSlice()).ToDeepEqual([]interface{}{4, 3, 77, 1})
 	expect(stack.ToSlice()).ToDeepEqual([]interface{}{4, 3, 2, 1})
 }
+
+func TestStackPrepend(t *testing.T) {
+	expect := expectFor(t)
+	stack := NewStack().Prepend(1).Prepend(2)
+	expect(stack.ToSlice()).ToDeepEqual([]interface{}{2, 1})
+	top, _ := stack.(Stack).Peek()
+	expect(top).ToBe(2)
+}
+
+func TestStackSlice(t *testing.T) {
+	expect := expectFor(t)
+	stack := NewStack().Push(4).Push(3).Push(2).Push(1)
+
+	suffix := stack.Slice(2, 4)
+	expect(suffix.ToSlice()).ToDeepEqual([]interface{}{3, 4})
+	expect(suffix).ToBe(stack.(*NonEmptyStack).tail.(*NonEmptyStack).tail)
+
+	middle := stack.Slice(1, 3)
+	expect(middle.Size()).ToBe(2)
+	expect(middle.ToSlice()).ToDeepEqual([]interface{}{2, 3})
+	expect(stack.Slice(1, 1).Size()).ToBe(0)
+	expect(stack.ToSlice()).ToDeepEqual([]interface{}{1, 2, 3, 4})
+
+	expect(NewStack().Slice(0, 0).Size()).ToBe(0)
+}
+
+func TestStackSliceOutOfRange(t *testing.T) {
+	expect := expectFor(t)
+	stack := NewStack().Push(2).Push(1)
+	expect(func() { stack.Slice(-1, 1) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { stack.Slice(0, 3) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { stack.Slice(2, 1) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { NewStack().Slice(0, 1) }).ToPanicWith(ErrIndexOutOfRange)
+}
+
+func TestStackGroupBy(t *testing.T) {
+	expect := expectFor(t)
+	stack := NewStack().Push(1).Push(2).Push(3)
+	groups := stack.GroupBy(func(v interface{}) interface{} {
+		return v.(int) % 2
+	})
+	group, _ := groups.Get(1)
+	expect(group.(Sequence).ToSlice()).ToDeepEqual([]interface{}{3, 1})
+
+	sums := stack.GroupByAggregate(func(v interface{}) interface{} {
+		return v.(int) % 2
+	}, 0, func(sum interface{}, v interface{}) interface{} {
+		return sum.(int) + v.(int)
+	})
+	sum, _ := sums.Get(1)
+	expect(sum).ToBe(4)
+
+	expect(NewStack().GroupBy(func(v interface{}) interface{} { return v }).(*HashMap).Size()).ToBe(0)
+}
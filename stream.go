@@ -43,6 +43,10 @@ func (iterable *Stream) ToSlice() []interface{} {
 	return toSliceHelper(iterable)
 }
 
+func (iterable *Stream) ToVector() *Vector {
+	return toVectorHelper(iterable)
+}
+
 func (iterable *Stream) Take(count int) Iterable {
 	return takeHelper(iterable, count)
 }
@@ -55,6 +59,14 @@ func (stream *Stream) SkipWhile(matchFn func(interface{}) bool) Iterable {
 	return skipWhileHelper(stream, matchFn)
 }
 
+func (stream *Stream) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(stream, groupFn)
+}
+
+func (stream *Stream) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(stream, groupFn, initialValue, reducerFn)
+}
+
 func (stream *Stream) Any(matchFn func(interface{}) bool) bool {
 	return anyHelper(stream, matchFn)
 }
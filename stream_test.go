@@ -132,3 +132,34 @@ func TestStreamSkipWhileSimple(t *testing.T) {
 
 	expect(actual).ToDeepEqual(expected)
 }
+
+func TestStreamGroupBy(t *testing.T) {
+	expect := expectFor(t)
+	data := []interface{}{"apple", "avocado", "banana", "blueberry", "apricot", "cherry"}
+	groups := buildStream(data).GroupBy(func(v interface{}) interface{} {
+		return v.(string)[:1]
+	})
+	expect(groups.(*HashMap).Size()).ToBe(3)
+	group, _ := groups.Get("a")
+	expect(group.(Sequence).ToSlice()).ToDeepEqual([]interface{}{"apple", "avocado", "apricot"})
+	group, _ = groups.Get("b")
+	expect(group.(Sequence).ToSlice()).ToDeepEqual([]interface{}{"banana", "blueberry"})
+	group, _ = groups.Get("c")
+	expect(group.(Sequence).Size()).ToBe(1)
+}
+
+func TestStreamGroupByAggregate(t *testing.T) {
+	expect := expectFor(t)
+	data := []interface{}{"apple", "avocado", "banana", "blueberry", "apricot", "cherry"}
+	lengths := buildStream(data).GroupByAggregate(func(v interface{}) interface{} {
+		return v.(string)[:1]
+	}, 0, func(total interface{}, v interface{}) interface{} {
+		return total.(int) + len(v.(string))
+	})
+	total, _ := lengths.Get("a")
+	expect(total).ToBe(19)
+	total, _ = lengths.Get("b")
+	expect(total).ToBe(15)
+	total, _ = lengths.Get("c")
+	expect(total).ToBe(6)
+}
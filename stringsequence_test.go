@@ -13,3 +13,11 @@ func TestStringSequenceIsActuallyASliceSequence(t *testing.T) {
 	expect(reflect.TypeOf(seq).AssignableTo(reflect.TypeOf(NewSliceSequence()))).ToBe(true)
 	expect(seq.ToSlice()).ToDeepEqual([]interface{}{'H', 'e', 'l', 'l', 'o'})
 }
+
+func TestStringSequencePrependAndSlice(t *testing.T) {
+	expect := expectFor(t)
+	seq := NewStringSequence("Hello")
+	expect(seq.Slice(1, 4).ToSlice()).ToDeepEqual([]interface{}{'e', 'l', 'l'})
+	expect(seq.Prepend('!').ToSlice()).ToDeepEqual([]interface{}{'!', 'H', 'e', 'l', 'l', 'o'})
+	expect(func() { seq.Slice(0, 6) }).ToPanicWith(ErrIndexOutOfRange)
+}
@@ -0,0 +1,87 @@
+package collections
+
+// A TransientHashMap is a mutable builder for a HashMap, modeled on
+// Clojure's transients. It is intended for bulk loading: rather than
+// copying the path to every updated entry, a transient edits the trie
+// nodes it has created in place, and only copies nodes it shares with
+// the HashMap it was made from.
+//
+// Persistent freezes the transient and returns an ordinary immutable
+// HashMap in O(1). Any use of the transient after that panics with
+// ErrTransientFrozen. Transients are not safe for concurrent use.
+type TransientHashMap struct {
+	owner  *transientOwner
+	hasher *keyHasher
+	size   int
+	root   HAMTNode
+}
+
+// Returns a transient holding the same entries as the map. The map
+// itself is never modified.
+func (hashMap *HashMap) Transient() *TransientHashMap {
+	return &TransientHashMap{
+		owner:  &transientOwner{},
+		hasher: hashMap.hasher,
+		size:   hashMap.size,
+		root:   hashMap.root,
+	}
+}
+
+// The number of entries in the transient
+func (transient *TransientHashMap) Size() int {
+	transient.ensureEditable()
+	return transient.size
+}
+
+func (transient *TransientHashMap) Contains(key interface{}) bool {
+	_, found := transient.Get(key)
+	return found
+}
+
+func (transient *TransientHashMap) Get(key interface{}) (interface{}, bool) {
+	transient.ensureEditable()
+	hash := transient.hasher.hash(key)
+	return transient.root.get(hash, 0, key)
+}
+
+// Sets the value for the key in place. Returns the transient
+// to allow chaining.
+func (transient *TransientHashMap) Set(key interface{}, value interface{}) *TransientHashMap {
+	transient.ensureEditable()
+	hash := transient.hasher.hash(key)
+	newRoot, howManyAdded := transient.root.set(transient.owner, hash, 0, key, value)
+	transient.root = newRoot
+	transient.size += howManyAdded
+	return transient
+}
+
+// Removes the key in place. Returns the transient
+// to allow chaining.
+func (transient *TransientHashMap) Remove(key interface{}) *TransientHashMap {
+	transient.ensureEditable()
+	hash := transient.hasher.hash(key)
+	newRoot, removed := transient.root.remove(transient.owner, hash, 0, key)
+	if removed {
+		transient.root = newRoot
+		transient.size -= 1
+	}
+	return transient
+}
+
+// Freezes the transient and returns a HashMap with its entries.
+// The transient can't be used afterwards.
+func (transient *TransientHashMap) Persistent() *HashMap {
+	transient.ensureEditable()
+	transient.owner = nil
+	return &HashMap{
+		hasher: transient.hasher,
+		size:   transient.size,
+		root:   transient.root,
+	}
+}
+
+func (transient *TransientHashMap) ensureEditable() {
+	if transient.owner == nil {
+		panic(ErrTransientFrozen)
+	}
+}
@@ -0,0 +1,127 @@
+package collections
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTransientHashMapBuild(t *testing.T) {
+	expect := expectFor(t)
+	transient := NewHashMap().Transient()
+	for i := 0; i < 5000; i++ {
+		transient.Set(i, i*2)
+	}
+	for i := 0; i < 5000; i += 3 {
+		transient.Remove(i)
+	}
+	hashMap := transient.Persistent()
+
+	expectCompactTrie(t, hashMap.root, 0)
+	expect(hashMap.Size()).ToBe(5000 - 1667)
+	for i := 0; i < 5000; i++ {
+		val, found := hashMap.Get(i)
+		expect(found).ToBe(i%3 != 0)
+		if found {
+			expect(val).ToBe(i * 2)
+		}
+	}
+	expect(len(hashMap.ToSlice())).ToBe(hashMap.Size())
+}
+
+func TestTransientHashMapDoesNotModifySource(t *testing.T) {
+	expect := expectFor(t)
+	random := fakerFor(t)
+	source := newHashMapWithHashFn(firstCharacterHash)
+	goMap := map[string]string{}
+	keys := []string{}
+	for i := 0; i < 1000; i++ {
+		key := random.String(1, 8)
+		keys = append(keys, key)
+		goMap[key] = key
+		source = source.set(key, key)
+	}
+
+	transient := source.Transient()
+	for i, key := range keys {
+		if i%2 == 0 {
+			transient.Remove(key)
+		} else {
+			transient.Set(key, "changed")
+		}
+		transient.Set(fmt.Sprintf("new-%d", i), i)
+	}
+	result := transient.Persistent()
+
+	expect(source.Size()).ToBe(len(goMap))
+	for k, v := range goMap {
+		val, found := source.Get(k)
+		expect(val).ToBe(v)
+		expect(found).ToBe(true)
+	}
+	expectCompactTrie(t, result.root, 0)
+	for i := 0; i < len(keys); i++ {
+		val, _ := result.Get(fmt.Sprintf("new-%d", i))
+		expect(val).ToBe(i)
+	}
+}
+
+func TestTransientHashMapPersistentIsImmutable(t *testing.T) {
+	expect := expectFor(t)
+	transient := NewHashMap().Transient().Set("a", 1).Set("b", 2)
+	frozen := transient.Persistent()
+	next := frozen.Transient().Set("a", 10).Remove("b").Persistent()
+
+	val, _ := frozen.Get("a")
+	expect(val).ToBe(1)
+	expect(frozen.Contains("b")).ToBe(true)
+	val, _ = next.Get("a")
+	expect(val).ToBe(10)
+	expect(next.Contains("b")).ToBe(false)
+}
+
+func TestTransientHashMapGetAndSize(t *testing.T) {
+	expect := expectFor(t)
+	transient := NewHashMap().Set("a", 1).(*HashMap).Transient()
+	transient.Set("b", 2).Set("a", 3)
+	val, found := transient.Get("a")
+	expect(val).ToBe(3)
+	expect(found).ToBe(true)
+	expect(transient.Contains("b")).ToBe(true)
+	expect(transient.Size()).ToBe(2)
+	transient.Remove("missing")
+	expect(transient.Size()).ToBe(2)
+}
+
+func TestTransientHashMapUseAfterPersistent(t *testing.T) {
+	expect := expectFor(t)
+	transient := NewHashMap().Transient().Set("a", 1)
+	transient.Persistent()
+	expect(func() { transient.Set("b", 2) }).ToPanicWith(ErrTransientFrozen)
+	expect(func() { transient.Remove("a") }).ToPanicWith(ErrTransientFrozen)
+	expect(func() { transient.Get("a") }).ToPanicWith(ErrTransientFrozen)
+	expect(func() { transient.Size() }).ToPanicWith(ErrTransientFrozen)
+	expect(func() { transient.Persistent() }).ToPanicWith(ErrTransientFrozen)
+}
+
+func BenchmarkHashMapBulkLoad(b *testing.B) {
+	keys := benchmarkKeys(100000)
+	b.Run("persistent", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			hashMap := NewHashMap()
+			for _, key := range keys {
+				hashMap = hashMap.set(key, key)
+			}
+		}
+	})
+	b.Run("transient", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			transient := NewHashMap().Transient()
+			for _, key := range keys {
+				transient.Set(key, key)
+			}
+			transient.Persistent()
+		}
+	})
+}
@@ -0,0 +1,519 @@
+package collections
+
+// A Vector is an immutable Sequence implemented as a relaxed radix
+// balanced (RRB) trie, which extends Clojure's PersistentVector with
+// efficient Concat, Slice and InsertAt. It is the right type for a
+// general purpose sequence.
+//
+// Elements are stored in leaves of up to 32, and every branch of the
+// trie has up to 32 children. A vector built by appending is a plain
+// bit-partitioned trie, where the index of an element, read five bits
+// at a time from the most significant end, is the path to it. Concat
+// and Slice may leave some leaves part full, so the branches above them
+// keep a table of their children's sizes to search instead. Either way
+// the trie is never more than a few levels deep, which makes Get and
+// Update O(log32 n). Updates copy only the path to the changed leaf.
+//
+// The last leaf is kept out of the trie as the tail, so Append and
+// Pop usually copy at most 32 elements, and only touch the trie once
+// every 32 calls. Both are amortized O(1).
+type Vector struct {
+	size  int
+	shift uint
+	root  *vectorNode
+	tail  []interface{}
+}
+
+// A node of a Vector trie. Leaves hold values, and branches hold
+// nodes. Slices are never appended to in place, because they may be
+// shared with other versions of the vector.
+//
+// Every child of a regular branch holds as many elements as fit below
+// it, except the last child, so the slot for an index is found by
+// shifting it. Concat and Slice make relaxed branches, whose children
+// may hold fewer, and which record where their children end in sizes.
+type vectorNode struct {
+	nodes  []*vectorNode
+	values []interface{}
+	// For a relaxed branch, sizes[i] is the number of elements in
+	// nodes[0] through nodes[i]. Nil for regular branches and leaves.
+	sizes []int
+}
+
+// Each level of the trie consumes the next 5 bits of the index
+const vectorBits uint = 5
+const vectorWidth int = 1 << vectorBits
+const vectorMask int = vectorWidth - 1
+
+// The size of the largest Vector, which is as many elements as an
+// int can count
+const maxVectorSize int = int(^uint(0) >> 1)
+
+// The root of every Vector whose elements all fit in its tail
+var emptyVectorNode = &vectorNode{}
+
+var _ Sequence = (*Vector)(nil)
+
+// Factory for Vectors
+func NewVector(values ...interface{}) *Vector {
+	slice := make([]interface{}, len(values))
+	copy(slice, values)
+	return vectorFromSlice(slice)
+}
+
+// Builds the trie bottom up from a slice the vector may keep. The
+// leaves share the slice, which is fine because they are never
+// edited in place.
+func vectorFromSlice(slice []interface{}) *Vector {
+	size := len(slice)
+	tailOffset := vectorTailOffset(size)
+	nodes := []*vectorNode{}
+	for start := 0; start < tailOffset; start += vectorWidth {
+		nodes = append(nodes, &vectorNode{values: slice[start : start+vectorWidth : start+vectorWidth]})
+	}
+	shift := vectorBits
+	for len(nodes) > vectorWidth {
+		parents := make([]*vectorNode, 0, (len(nodes)+vectorMask)/vectorWidth)
+		for start := 0; start < len(nodes); start += vectorWidth {
+			end := start + vectorWidth
+			if end > len(nodes) {
+				end = len(nodes)
+			}
+			parents = append(parents, &vectorNode{nodes: nodes[start:end:end]})
+		}
+		nodes = parents
+		shift += vectorBits
+	}
+	root := emptyVectorNode
+	if len(nodes) > 0 {
+		root = &vectorNode{nodes: nodes}
+	}
+	return &Vector{
+		size:  size,
+		shift: shift,
+		root:  root,
+		tail:  slice[tailOffset:size:size],
+	}
+}
+
+// The index of the first element in the tail of a vector of the
+// given size, when the vector was built from a slice or by appending.
+// The tail holds between 1 and 32 elements, unless the vector is empty.
+func vectorTailOffset(size int) int {
+	if size < vectorWidth {
+		return 0
+	}
+	return ((size - 1) >> vectorBits) << vectorBits
+}
+
+// Sequence Methods
+
+func (vector *Vector) Size() int {
+	return vector.size
+}
+
+func (vector *Vector) Get(index int) interface{} {
+	if index < 0 || index >= vector.size {
+		panic(ErrIndexOutOfRange)
+	}
+	leaf, start := vector.leafFor(index)
+	return leaf[index-start]
+}
+
+func (vector *Vector) Update(index int, value interface{}) Sequence {
+	return vector.update(index, value)
+}
+
+func (vector *Vector) update(index int, value interface{}) *Vector {
+	if index < 0 || index >= vector.size {
+		panic(ErrIndexOutOfRange)
+	}
+	tailOffset := vector.tailOffset()
+	if index >= tailOffset {
+		tail := copyValues(vector.tail, len(vector.tail))
+		tail[index-tailOffset] = value
+		return &Vector{
+			size:  vector.size,
+			shift: vector.shift,
+			root:  vector.root,
+			tail:  tail,
+		}
+	}
+	return &Vector{
+		size:  vector.size,
+		shift: vector.shift,
+		root:  updateVectorNode(vector.root, vector.shift, index, value),
+		tail:  vector.tail,
+	}
+}
+
+func (vector *Vector) Append(value interface{}) Sequence {
+	return vector.append(value)
+}
+
+func (vector *Vector) append(value interface{}) *Vector {
+	if vector.size == maxVectorSize {
+		panic(ErrVectorTooLarge)
+	}
+	if len(vector.tail) < vectorWidth {
+		tail := copyValues(vector.tail, len(vector.tail)+1)
+		tail[len(vector.tail)] = value
+		return &Vector{
+			size:  vector.size + 1,
+			shift: vector.shift,
+			root:  vector.root,
+			tail:  tail,
+		}
+	}
+	// The tail is full, so it moves into the trie and a new
+	// tail starts with the value
+	root, shift := vector.pushTail()
+	return &Vector{
+		size:  vector.size + 1,
+		shift: shift,
+		root:  root,
+		tail:  []interface{}{value},
+	}
+}
+
+// Returns the root and shift of the trie with the tail added
+// as its last leaf
+func (vector *Vector) pushTail() (*vectorNode, uint) {
+	leaf := &vectorNode{values: vector.tail}
+	if root := pushVectorLeaf(vector.root, vector.shift, leaf); root != nil {
+		return root, vector.shift
+	}
+	// The trie is full, so it grows a level
+	shift := vector.shift + vectorBits
+	return newVectorBranch([]*vectorNode{vector.root, newVectorPath(vector.shift, leaf)}, shift), shift
+}
+
+// Prepends in O(log n), by concatenating the vector to a vector
+// holding just the value
+func (vector *Vector) Prepend(value interface{}) Sequence {
+	return NewVector(value).Concat(vector)
+}
+
+// Returns a new Vector with the last element removed, along
+// with that element. If the Vector is empty the boolean third
+// return value will be false and the popped value will be nil.
+func (vector *Vector) Pop() (*Vector, interface{}, bool) {
+	if vector.size == 0 {
+		return vector, nil, false
+	}
+	last := vector.tail[len(vector.tail)-1]
+	if vector.size == 1 {
+		return NewVector(), last, true
+	}
+	if len(vector.tail) > 1 {
+		return &Vector{
+			size:  vector.size - 1,
+			shift: vector.shift,
+			root:  vector.root,
+			tail:  vector.tail[: len(vector.tail)-1 : len(vector.tail)-1],
+		}, last, true
+	}
+	// The tail is empty, so the last leaf of the trie
+	// becomes the tail
+	root, tail := popVectorLeaf(vector.root, vector.shift)
+	root, shift := trimVectorRoot(root, vector.shift)
+	return &Vector{
+		size:  vector.size - 1,
+		shift: shift,
+		root:  root,
+		tail:  tail,
+	}, last, true
+}
+
+// Returns the last element of the Vector. If the Vector is empty
+// the return value will be nil and the added boolean flag will be false.
+func (vector *Vector) Peek() (interface{}, bool) {
+	if vector.size == 0 {
+		return nil, false
+	}
+	return vector.tail[len(vector.tail)-1], true
+}
+
+func (vector *Vector) tailOffset() int {
+	return vector.size - len(vector.tail)
+}
+
+// Returns the leaf or tail holding the element at index, which
+// must be in range, and the index of the first element it holds
+func (vector *Vector) leafFor(index int) ([]interface{}, int) {
+	tailOffset := vector.tailOffset()
+	if index >= tailOffset {
+		return vector.tail, tailOffset
+	}
+	node, start := vector.root, 0
+	for level := vector.shift; level > 0; level -= vectorBits {
+		slot, offset := node.slotFor(level, index-start)
+		node, start = node.nodes[slot], start+offset
+	}
+	return node.values, start
+}
+
+// Returns the slot of the child of the branch at level that holds
+// the element at index, counting from the start of the branch, and
+// the number of elements in the children before it
+func (node *vectorNode) slotFor(level uint, index int) (int, int) {
+	slot := index >> level
+	if node.sizes == nil {
+		return slot, slot << level
+	}
+	// Children hold at most 1<<level elements, so the slot is
+	// at least as far as it would be in a regular branch
+	for node.sizes[slot] <= index {
+		slot += 1
+	}
+	if slot == 0 {
+		return 0, 0
+	}
+	return slot, node.sizes[slot-1]
+}
+
+// The number of elements below the node at level. O(1) for leaves
+// and relaxed branches, and O(log n) for regular branches.
+func vectorNodeSize(node *vectorNode, level uint) int {
+	if level == 0 {
+		return len(node.values)
+	}
+	count := len(node.nodes)
+	if count == 0 {
+		return 0
+	}
+	if node.sizes != nil {
+		return node.sizes[count-1]
+	}
+	return (count-1)<<level + vectorNodeSize(node.nodes[count-1], level-vectorBits)
+}
+
+// Returns a branch at level with the given children, relaxed only
+// if one of them other than the last holds fewer elements than fit
+func newVectorBranch(nodes []*vectorNode, level uint) *vectorNode {
+	sizes := make([]int, len(nodes))
+	regular := true
+	total := 0
+	for i, child := range nodes {
+		size := vectorNodeSize(child, level-vectorBits)
+		if i < len(nodes)-1 && size != 1<<level {
+			regular = false
+		}
+		total += size
+		sizes[i] = total
+	}
+	if regular {
+		return &vectorNode{nodes: nodes}
+	}
+	return &vectorNode{nodes: nodes, sizes: sizes}
+}
+
+// Returns the root and shift of a trie with the given root, without
+// the branches at the top that have a single child. A nil root is
+// an empty trie.
+func trimVectorRoot(root *vectorNode, shift uint) (*vectorNode, uint) {
+	if root == nil {
+		return emptyVectorNode, vectorBits
+	}
+	for shift > vectorBits && len(root.nodes) == 1 {
+		root = root.nodes[0]
+		shift -= vectorBits
+	}
+	return root, shift
+}
+
+// Returns a copy of values with the given length, which is at least
+// as long as values
+func copyValues(values []interface{}, length int) []interface{} {
+	result := make([]interface{}, length)
+	copy(result, values)
+	return result
+}
+
+// Returns a copy of nodes with the given length, which is at least
+// as long as nodes
+func copyVectorNodes(nodes []*vectorNode, length int) []*vectorNode {
+	result := make([]*vectorNode, length)
+	copy(result, nodes)
+	return result
+}
+
+// Returns a copy of the node at level with the element at index,
+// counting from the start of the node, replaced by value
+func updateVectorNode(node *vectorNode, level uint, index int, value interface{}) *vectorNode {
+	if level == 0 {
+		values := copyValues(node.values, len(node.values))
+		values[index] = value
+		return &vectorNode{values: values}
+	}
+	slot, offset := node.slotFor(level, index)
+	nodes := copyVectorNodes(node.nodes, len(node.nodes))
+	nodes[slot] = updateVectorNode(nodes[slot], level-vectorBits, index-offset, value)
+	return &vectorNode{nodes: nodes, sizes: node.sizes}
+}
+
+// Returns a chain of branches from level down to the leaf
+func newVectorPath(level uint, leaf *vectorNode) *vectorNode {
+	if level == 0 {
+		return leaf
+	}
+	return &vectorNode{nodes: []*vectorNode{newVectorPath(level-vectorBits, leaf)}}
+}
+
+// Returns a copy of the node at level with leaf added after its last
+// leaf, or nil if there is no room for it below the node
+func pushVectorLeaf(node *vectorNode, level uint, leaf *vectorNode) *vectorNode {
+	count := len(node.nodes)
+	if level > vectorBits && count > 0 {
+		if child := pushVectorLeaf(node.nodes[count-1], level-vectorBits, leaf); child != nil {
+			nodes := copyVectorNodes(node.nodes, count)
+			nodes[count-1] = child
+			var sizes []int
+			if node.sizes != nil {
+				sizes = make([]int, count)
+				copy(sizes, node.sizes)
+				sizes[count-1] += len(leaf.values)
+			}
+			return &vectorNode{nodes: nodes, sizes: sizes}
+		}
+	}
+	if count == vectorWidth {
+		return nil
+	}
+	nodes := copyVectorNodes(node.nodes, count+1)
+	nodes[count] = newVectorPath(level-vectorBits, leaf)
+	if node.sizes == nil && (count == 0 || vectorNodeSize(node.nodes[count-1], level-vectorBits) == 1<<level) {
+		return &vectorNode{nodes: nodes}
+	}
+	return newVectorBranch(nodes, level)
+}
+
+// Returns a copy of the node at level without its last leaf, or
+// nil if nothing would be left, along with the values of that leaf
+func popVectorLeaf(node *vectorNode, level uint) (*vectorNode, []interface{}) {
+	count := len(node.nodes)
+	var child *vectorNode
+	var leaf []interface{}
+	if level == vectorBits {
+		leaf = node.nodes[count-1].values
+	} else {
+		child, leaf = popVectorLeaf(node.nodes[count-1], level-vectorBits)
+	}
+	if child == nil {
+		if count == 1 {
+			return nil, leaf
+		}
+		var sizes []int
+		if node.sizes != nil {
+			sizes = node.sizes[: count-1 : count-1]
+		}
+		return &vectorNode{nodes: node.nodes[: count-1 : count-1], sizes: sizes}, leaf
+	}
+	nodes := copyVectorNodes(node.nodes, count)
+	nodes[count-1] = child
+	var sizes []int
+	if node.sizes != nil {
+		sizes = make([]int, count)
+		copy(sizes, node.sizes)
+		sizes[count-1] -= len(leaf)
+	}
+	return &vectorNode{nodes: nodes, sizes: sizes}, leaf
+}
+
+// Iterable Methods
+
+func (vector *Vector) Iterator() Iterator {
+	return &VectorIterator{
+		vector: vector,
+		index:  -1,
+	}
+}
+
+func (vector *Vector) ForEach(iterFn func(interface{})) {
+	forEachHelper(vector, iterFn)
+}
+
+func (vector *Vector) Map(mapFn func(interface{}) interface{}) Iterable {
+	return mapHelper(vector, mapFn)
+}
+
+func (vector *Vector) Filter(filterFn func(interface{}) bool) Iterable {
+	return filterHelper(vector, filterFn)
+}
+
+func (vector *Vector) Fold(initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) interface{} {
+	return foldHelper(vector, initialValue, reducerFn)
+}
+
+func (vector *Vector) ToSlice() []interface{} {
+	return toSliceHelper(vector)
+}
+
+func (vector *Vector) ToVector() *Vector {
+	return vector
+}
+
+func (vector *Vector) Take(count int) Iterable {
+	return takeHelper(vector, count)
+}
+
+func (vector *Vector) Skip(count int) Iterable {
+	return skipHelper(vector, count)
+}
+
+func (vector *Vector) SkipWhile(matchFn func(interface{}) bool) Iterable {
+	return skipWhileHelper(vector, matchFn)
+}
+
+func (vector *Vector) GroupBy(groupFn func(interface{}) interface{}) Map {
+	return groupByHelper(vector, groupFn)
+}
+
+func (vector *Vector) GroupByAggregate(groupFn func(interface{}) interface{}, initialValue interface{}, reducerFn func(interface{}, interface{}) interface{}) Map {
+	return groupByAggregateHelper(vector, groupFn, initialValue, reducerFn)
+}
+
+func (vector *Vector) Any(matchFn func(interface{}) bool) bool {
+	return anyHelper(vector, matchFn)
+}
+
+// Hashable Methods
+
+// Returns true if other is a Sequence with the same elements in the same order
+func (vector *Vector) Equals(other interface{}) bool {
+	return equalSequences(vector, other)
+}
+
+func (vector *Vector) Hash() uint64 {
+	return hashOfSequence(vector)
+}
+
+// An Iterator over the elements of a Vector in order. It walks
+// the trie once per leaf rather than once per element.
+type VectorIterator struct {
+	vector     *Vector
+	index      int
+	chunkStart int
+	chunk      []interface{}
+}
+
+func (iterator *VectorIterator) MoveNext() bool {
+	if iterator.index >= iterator.vector.size {
+		return false
+	}
+	iterator.index += 1
+	if iterator.index >= iterator.vector.size {
+		return false
+	}
+	if iterator.index-iterator.chunkStart >= len(iterator.chunk) {
+		iterator.chunk, iterator.chunkStart = iterator.vector.leafFor(iterator.index)
+	}
+	return true
+}
+
+func (iterator *VectorIterator) Current() interface{} {
+	if iterator.index < 0 || iterator.index >= iterator.vector.size {
+		panic(ErrIterationOutOfRange)
+	}
+	return iterator.chunk[iterator.index-iterator.chunkStart]
+}
@@ -0,0 +1,269 @@
+package collections
+
+// This file contains the operations that make a Vector's trie relaxed:
+// Concat, Slice and InsertAt.
+//
+// Concatenation follows Bagwell and Rompf, "RRB-Trees: Efficient
+// Immutable Vectors", as refined in L'orange's thesis "Improving RRB-Tree
+// Performance through Transience". The two tries are zipped together
+// down their facing edges, and at each level the nodes along the seam
+// are redistributed so that there are at most vectorExtraSteps more of
+// them than a fully packed level would need. That keeps the search
+// through a relaxed branch's sizes short, while only the nodes along
+// the seam are rebuilt.
+
+// How many more nodes than the fewest possible a level of the
+// seam may keep after a concatenation
+const vectorExtraSteps = 2
+
+// Returns a vector with the elements of this vector followed by
+// those of other. O(log n).
+func (vector *Vector) Concat(other *Vector) *Vector {
+	if other.size == 0 {
+		return vector
+	}
+	if vector.size == 0 {
+		return other
+	}
+	if vector.size > maxVectorSize-other.size {
+		panic(ErrVectorTooLarge)
+	}
+	if other.size == len(other.tail) {
+		// Other has no trie to join, so its few elements are
+		// simply appended
+		result := vector
+		for _, value := range other.tail {
+			result = result.append(value)
+		}
+		return result
+	}
+	leftRoot, leftShift := vector.pushTail()
+	shift := leftShift
+	if other.shift > shift {
+		shift = other.shift
+	}
+	root, shift := trimVectorRoot(concatVectorNodes(leftRoot, leftShift, other.root, other.shift), shift+vectorBits)
+	return &Vector{
+		size:  vector.size + other.size,
+		shift: shift,
+		root:  root,
+		tail:  other.tail,
+	}
+}
+
+// Returns a new sequence with the elements from start up to but not
+// including end. Panics with ErrIndexOutOfRange unless
+// 0 <= start <= end <= Size(). O(log n), and shares the elements of
+// the vector rather than copying them.
+func (vector *Vector) Slice(start int, end int) Sequence {
+	return vector.slice(start, end)
+}
+
+func (vector *Vector) slice(start int, end int) *Vector {
+	if start < 0 || end > vector.size || start > end {
+		panic(ErrIndexOutOfRange)
+	}
+	if start == 0 && end == vector.size {
+		return vector
+	}
+	if start == end {
+		return NewVector()
+	}
+	tailOffset := vector.tailOffset()
+	if start >= tailOffset {
+		return &Vector{
+			size:  end - start,
+			shift: vectorBits,
+			root:  emptyVectorNode,
+			tail:  vector.tail[start-tailOffset : end-tailOffset : end-tailOffset],
+		}
+	}
+	treeEnd := end
+	if treeEnd > tailOffset {
+		treeEnd = tailOffset
+	}
+	root, shift := trimVectorRoot(sliceVectorNode(vector.root, vector.shift, start, treeEnd), vector.shift)
+	if end > tailOffset {
+		return &Vector{
+			size:  end - start,
+			shift: shift,
+			root:  root,
+			tail:  vector.tail[: end-tailOffset : end-tailOffset],
+		}
+	}
+	// The slice ends inside the trie, so its last leaf
+	// becomes the tail
+	root, tail := popVectorLeaf(root, shift)
+	root, shift = trimVectorRoot(root, shift)
+	return &Vector{
+		size:  end - start,
+		shift: shift,
+		root:  root,
+		tail:  tail,
+	}
+}
+
+// Returns a vector with value inserted at index, moving the elements
+// from index onwards along by one. Panics with ErrIndexOutOfRange
+// unless 0 <= index <= Size(). O(log n).
+func (vector *Vector) InsertAt(index int, value interface{}) *Vector {
+	if index < 0 || index > vector.size {
+		panic(ErrIndexOutOfRange)
+	}
+	if index == vector.size {
+		return vector.append(value)
+	}
+	return vector.slice(0, index).append(value).Concat(vector.slice(index, vector.size))
+}
+
+// Returns a node one level above the higher of left and right, which
+// are nodes at leftLevel and rightLevel, holding the elements of left
+// followed by those of right
+func concatVectorNodes(left *vectorNode, leftLevel uint, right *vectorNode, rightLevel uint) *vectorNode {
+	if leftLevel > rightLevel {
+		last := len(left.nodes) - 1
+		middle := concatVectorNodes(left.nodes[last], leftLevel-vectorBits, right, rightLevel)
+		return rebalanceVectorNodes(left.nodes[:last], middle.nodes, nil, leftLevel)
+	}
+	if leftLevel < rightLevel {
+		middle := concatVectorNodes(left, leftLevel, right.nodes[0], rightLevel-vectorBits)
+		return rebalanceVectorNodes(nil, middle.nodes, right.nodes[1:], rightLevel)
+	}
+	if leftLevel == 0 {
+		if len(left.values)+len(right.values) <= vectorWidth {
+			values := copyValues(left.values, len(left.values)+len(right.values))
+			copy(values[len(left.values):], right.values)
+			return &vectorNode{nodes: []*vectorNode{{values: values}}}
+		}
+		return newVectorBranch([]*vectorNode{left, right}, vectorBits)
+	}
+	last := len(left.nodes) - 1
+	middle := concatVectorNodes(left.nodes[last], leftLevel-vectorBits, right.nodes[0], rightLevel-vectorBits)
+	return rebalanceVectorNodes(left.nodes[:last], middle.nodes, right.nodes[1:], leftLevel)
+}
+
+// Returns a node at level+5 holding the nodes at level-5 in left,
+// middle and right, in that order, redistributed so that there are
+// few enough of them
+func rebalanceVectorNodes(left []*vectorNode, middle []*vectorNode, right []*vectorNode, level uint) *vectorNode {
+	nodes := make([]*vectorNode, 0, len(left)+len(middle)+len(right))
+	nodes = append(nodes, left...)
+	nodes = append(nodes, middle...)
+	nodes = append(nodes, right...)
+	childLevel := level - vectorBits
+	nodes = executeVectorConcatPlan(nodes, vectorConcatPlan(nodes, childLevel), childLevel)
+	if len(nodes) <= vectorWidth {
+		return &vectorNode{nodes: []*vectorNode{newVectorBranch(nodes, level)}}
+	}
+	return newVectorBranch([]*vectorNode{
+		newVectorBranch(nodes[:vectorWidth:vectorWidth], level),
+		newVectorBranch(nodes[vectorWidth:], level),
+	}, level+vectorBits)
+}
+
+// The number of children of a branch at level, or of values of a leaf
+func vectorNodeLength(node *vectorNode, level uint) int {
+	if level == 0 {
+		return len(node.values)
+	}
+	return len(node.nodes)
+}
+
+// Returns how many children or values each of the nodes at level
+// should have once redistributed. Starting from the left, the contents
+// of each node that is not close to full are spread over the nodes
+// after it, until the number of nodes is within vectorExtraSteps
+// of the fewest that could hold them.
+func vectorConcatPlan(nodes []*vectorNode, level uint) []int {
+	plan := make([]int, len(nodes))
+	total := 0
+	for i, node := range nodes {
+		plan[i] = vectorNodeLength(node, level)
+		total += plan[i]
+	}
+	fewest := (total + vectorWidth - 1) / vectorWidth
+	length := len(plan)
+	i := 0
+	for fewest+vectorExtraSteps < length {
+		for plan[i] > vectorWidth-vectorExtraSteps/2 {
+			i += 1
+		}
+		remaining := plan[i]
+		for remaining > 0 {
+			size := remaining + plan[i+1]
+			if size > vectorWidth {
+				size = vectorWidth
+			}
+			plan[i] = size
+			remaining += plan[i+1] - size
+			i += 1
+		}
+		copy(plan[i:length-1], plan[i+1:length])
+		length -= 1
+		i -= 1
+	}
+	return plan[:length]
+}
+
+// Returns nodes at level with the contents of the given nodes, in
+// order, and the number of children or values given by the plan.
+// Nodes that are already the planned size are kept as they are.
+func executeVectorConcatPlan(nodes []*vectorNode, plan []int, level uint) []*vectorNode {
+	result := make([]*vectorNode, 0, len(plan))
+	source, offset := 0, 0
+	for _, size := range plan {
+		if offset == 0 && vectorNodeLength(nodes[source], level) == size {
+			result = append(result, nodes[source])
+			source += 1
+			continue
+		}
+		var values []interface{}
+		var children []*vectorNode
+		for filled := 0; filled < size; {
+			length := vectorNodeLength(nodes[source], level)
+			taken := length - offset
+			if taken > size-filled {
+				taken = size - filled
+			}
+			if level == 0 {
+				values = append(values, nodes[source].values[offset:offset+taken]...)
+			} else {
+				children = append(children, nodes[source].nodes[offset:offset+taken]...)
+			}
+			filled += taken
+			offset += taken
+			if offset == length {
+				source, offset = source+1, 0
+			}
+		}
+		if level == 0 {
+			result = append(result, &vectorNode{values: values})
+		} else {
+			result = append(result, newVectorBranch(children, level))
+		}
+	}
+	return result
+}
+
+// Returns a node at level with the elements of node from start up
+// to but not including end, counting from the start of the node
+func sliceVectorNode(node *vectorNode, level uint, start int, end int) *vectorNode {
+	if start == 0 && end == vectorNodeSize(node, level) {
+		return node
+	}
+	if level == 0 {
+		return &vectorNode{values: node.values[start:end:end]}
+	}
+	first, firstOffset := node.slotFor(level, start)
+	last, lastOffset := node.slotFor(level, end-1)
+	childLevel := level - vectorBits
+	if first == last {
+		child := sliceVectorNode(node.nodes[first], childLevel, start-firstOffset, end-firstOffset)
+		return newVectorBranch([]*vectorNode{child}, level)
+	}
+	nodes := copyVectorNodes(node.nodes[first:last+1], last-first+1)
+	firstChild := nodes[0]
+	nodes[0] = sliceVectorNode(firstChild, childLevel, start-firstOffset, vectorNodeSize(firstChild, childLevel))
+	nodes[last-first] = sliceVectorNode(nodes[last-first], childLevel, 0, end-lastOffset)
+	return newVectorBranch(nodes, level)
+}
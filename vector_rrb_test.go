@@ -0,0 +1,226 @@
+package collections
+
+import (
+	"testing"
+)
+
+// Checks the shape of a vector's trie: every node is non-empty and no
+// wider than 32, the leaves are all at the bottom, the size tables of
+// relaxed branches are right, and the children of regular branches
+// other than the last are full
+func expectVectorInvariants(t *testing.T, vector *Vector) {
+	t.Helper()
+	var check func(node *vectorNode, level uint) int
+	check = func(node *vectorNode, level uint) int {
+		if level == 0 {
+			if node.nodes != nil || len(node.values) == 0 || len(node.values) > vectorWidth {
+				t.Fatalf("bad leaf with %d values and %d nodes", len(node.values), len(node.nodes))
+			}
+			return len(node.values)
+		}
+		if node.values != nil || len(node.nodes) == 0 || len(node.nodes) > vectorWidth {
+			t.Fatalf("bad branch with %d nodes and %d values at level %d", len(node.nodes), len(node.values), level)
+		}
+		if node.sizes != nil && len(node.sizes) != len(node.nodes) {
+			t.Fatalf("%d sizes for %d nodes", len(node.sizes), len(node.nodes))
+		}
+		total := 0
+		for i, child := range node.nodes {
+			size := check(child, level-vectorBits)
+			total += size
+			if node.sizes != nil && node.sizes[i] != total {
+				t.Fatalf("size table says %d, but the children hold %d", node.sizes[i], total)
+			}
+			if node.sizes == nil && i < len(node.nodes)-1 && size != 1<<level {
+				t.Fatalf("regular branch has a child with %d elements", size)
+			}
+		}
+		return total
+	}
+	if vector.size == 0 {
+		if len(vector.tail) != 0 || vector.root != emptyVectorNode {
+			t.Fatalf("empty vector has elements")
+		}
+		return
+	}
+	if len(vector.tail) == 0 || len(vector.tail) > vectorWidth {
+		t.Fatalf("tail has %d elements", len(vector.tail))
+	}
+	if vector.shift < vectorBits {
+		t.Fatalf("root is at level %d", vector.shift)
+	}
+	treeSize := 0
+	if len(vector.root.nodes) > 0 {
+		treeSize = check(vector.root, vector.shift)
+	}
+	if treeSize+len(vector.tail) != vector.size {
+		t.Fatalf("vector size is %d, but it holds %d", vector.size, treeSize+len(vector.tail))
+	}
+}
+
+func concatSlices(slices ...[]interface{}) []interface{} {
+	result := []interface{}{}
+	for _, slice := range slices {
+		result = append(result, slice...)
+	}
+	return result
+}
+
+func TestVectorConcat(t *testing.T) {
+	sizes := []int{0, 1, 5, 31, 32, 33, 100, 1024, 1057, 5000, largeVectorSize}
+	for _, leftSize := range sizes {
+		for _, rightSize := range sizes {
+			left := rangeSlice(leftSize)
+			right := make([]interface{}, rightSize)
+			for i := range right {
+				right[i] = -i
+			}
+			vector := NewVector(left...).Concat(NewVector(right...))
+			expectVectorInvariants(t, vector)
+			expectSequenceMatches(t, vector, concatSlices(left, right))
+		}
+	}
+}
+
+func TestVectorConcatIsImmutable(t *testing.T) {
+	left := NewVector(rangeSlice(1000)...)
+	right := NewVector(rangeSlice(2000)...)
+	both := left.Concat(right)
+	both = both.update(500, "left").update(1500, "right")
+	both.Pop()
+	expectSequenceMatches(t, left, rangeSlice(1000))
+	expectSequenceMatches(t, right, rangeSlice(2000))
+}
+
+func TestVectorConcatManySmallVectors(t *testing.T) {
+	expect := expectFor(t)
+	vector := NewVector()
+	model := []interface{}{}
+	for i := 0; i < 3000; i++ {
+		piece := rangeSlice(i % 7)
+		vector = vector.Concat(NewVector(piece...))
+		model = append(model, piece...)
+		// Pushing a part full tail into the trie each time
+		// must not leave a trail of tiny leaves
+		if i%100 == 0 {
+			expectVectorInvariants(t, vector)
+		}
+	}
+	expectVectorInvariants(t, vector)
+	expectSequenceMatches(t, vector, model)
+	expect(vector.shift <= 2*vectorBits).ToBe(true)
+}
+
+func TestVectorSlice(t *testing.T) {
+	expect := expectFor(t)
+	for _, size := range []int{0, 1, 32, 33, 100, 1057, largeVectorSize} {
+		model := rangeSlice(size)
+		vector := NewVector(model...)
+		bounds := []int{0, 1, 31, 32, 33, size / 3, size / 2, size - 33, size - 32, size - 1, size}
+		for _, start := range bounds {
+			for _, end := range bounds {
+				if start < 0 || end > size || start > end {
+					continue
+				}
+				slice := vector.slice(start, end)
+				expectVectorInvariants(t, slice)
+				expectSequenceMatches(t, slice, model[start:end])
+			}
+		}
+		expect(vector.Slice(0, size)).ToBe(vector)
+	}
+}
+
+func TestVectorSliceOutOfRange(t *testing.T) {
+	expect := expectFor(t)
+	vector := NewVector(1, 2, 3)
+	expect(func() { vector.Slice(-1, 2) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { vector.Slice(0, 4) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { vector.Slice(2, 1) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { vector.InsertAt(-1, 0) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { vector.InsertAt(4, 0) }).ToPanicWith(ErrIndexOutOfRange)
+}
+
+func TestVectorInsertAt(t *testing.T) {
+	vector := NewVector()
+	model := []interface{}{}
+	random := fakerFor(t)
+	for i := 0; i < 2000; i++ {
+		index := random.rand.Intn(len(model) + 1)
+		vector = vector.InsertAt(index, i)
+		model = concatSlices(model[:index], []interface{}{i}, model[index:])
+	}
+	expectVectorInvariants(t, vector)
+	expectSequenceMatches(t, vector, model)
+}
+
+// Applies random operations to a vector and to a slice, and checks
+// that they always agree
+func TestVectorMatchesSliceModel(t *testing.T) {
+	random := fakerFor(t)
+	type state struct {
+		vector *Vector
+		model  []interface{}
+	}
+	states := []state{{NewVector(), []interface{}{}}}
+	pick := func() state {
+		return states[random.rand.Intn(len(states))]
+	}
+	next := 0
+	for step := 0; step < 3000; step++ {
+		current := pick()
+		vector, model := current.vector, current.model
+		switch random.rand.Intn(7) {
+		case 0:
+			count := random.rand.Intn(100)
+			for i := 0; i < count; i++ {
+				vector = vector.append(next)
+				model = concatSlices(model, []interface{}{next})
+				next++
+			}
+		case 1:
+			count := random.rand.Intn(50)
+			for i := 0; i < count && len(model) > 0; i++ {
+				popped, value, _ := vector.Pop()
+				if value != model[len(model)-1] {
+					t.Fatalf("popped %v, expected %v", value, model[len(model)-1])
+				}
+				vector, model = popped, model[:len(model)-1]
+			}
+		case 2:
+			if len(model) > 0 {
+				index := random.rand.Intn(len(model))
+				vector = vector.update(index, next)
+				model = concatSlices(model)
+				model[index] = next
+				next++
+			}
+		case 3:
+			other := pick()
+			vector = vector.Concat(other.vector)
+			model = concatSlices(model, other.model)
+		case 4:
+			start := random.rand.Intn(len(model) + 1)
+			end := start + random.rand.Intn(len(model)-start+1)
+			vector = vector.slice(start, end)
+			model = model[start:end]
+		case 5:
+			index := random.rand.Intn(len(model) + 1)
+			vector = vector.InsertAt(index, next)
+			model = concatSlices(model[:index], []interface{}{next}, model[index:])
+			next++
+		case 6:
+			model = rangeSlice(random.rand.Intn(3000))
+			vector = NewVector(model...)
+		}
+		expectVectorInvariants(t, vector)
+		expectSequenceMatches(t, vector, model)
+		if len(model) < 100000 {
+			states = append(states, state{vector, model})
+		}
+	}
+	// Earlier versions are unaffected by everything derived from them
+	for _, state := range states {
+		expectSequenceMatches(t, state.vector, state.model)
+	}
+}
@@ -0,0 +1,197 @@
+package collections
+
+import (
+	"testing"
+)
+
+// Enough elements for a trie three levels deep
+const largeVectorSize = 32*32*32 + 100
+
+func rangeSlice(size int) []interface{} {
+	slice := make([]interface{}, size)
+	for i := range slice {
+		slice[i] = i
+	}
+	return slice
+}
+
+// Checks that the sequence holds exactly the elements of model, in
+// order, both by index and by iteration
+func expectSequenceMatches(t *testing.T, sequence Sequence, model []interface{}) {
+	t.Helper()
+	if sequence.Size() != len(model) {
+		t.Fatalf("expected size %d, got %d", len(model), sequence.Size())
+	}
+	for i, value := range model {
+		if sequence.Get(i) != value {
+			t.Fatalf("expected %v at %d, got %v", value, i, sequence.Get(i))
+		}
+	}
+	i := 0
+	iterator := sequence.Iterator()
+	for iterator.MoveNext() {
+		if iterator.Current() != model[i] {
+			t.Fatalf("expected to iterate %v at %d, got %v", model[i], i, iterator.Current())
+		}
+		i++
+	}
+	if i != len(model) {
+		t.Fatalf("expected to iterate %d elements, got %d", len(model), i)
+	}
+}
+
+func TestEmptyVector(t *testing.T) {
+	expect := expectFor(t)
+	vector := NewVector()
+	expect(vector.Size()).ToBe(0)
+	expect(vector.ToSlice()).ToDeepEqual([]interface{}{})
+	expect(vector.Iterator().MoveNext()).ToBe(false)
+	_, found := vector.Peek()
+	expect(found).ToBe(false)
+	popped, _, found := vector.Pop()
+	expect(found).ToBe(false)
+	expect(popped).ToBe(vector)
+}
+
+func TestVectorAppendIsImmutable(t *testing.T) {
+	vectors := []*Vector{}
+	vector := NewVector()
+	for i := 0; i < 2000; i++ {
+		vectors = append(vectors, vector)
+		vector = vector.Append(i).(*Vector)
+	}
+	model := rangeSlice(2000)
+	for i, vector := range vectors {
+		expectSequenceMatches(t, vector, model[:i])
+	}
+}
+
+func TestVectorAppendGrowsTheTrie(t *testing.T) {
+	vector := NewVector()
+	for i := 0; i < largeVectorSize; i++ {
+		vector = vector.append(i)
+	}
+	expectSequenceMatches(t, vector, rangeSlice(largeVectorSize))
+	expectFor(t)(vector.shift).ToBe(3 * vectorBits)
+}
+
+func TestNewVectorMatchesAppend(t *testing.T) {
+	expect := expectFor(t)
+	for _, size := range []int{0, 1, 31, 32, 33, 64, 65, 32*32 + 32, 32*32 + 33, largeVectorSize} {
+		model := rangeSlice(size)
+		built := NewVector(model...)
+		expectSequenceMatches(t, built, model)
+		appended := NewVector()
+		for _, value := range model {
+			appended = appended.append(value)
+		}
+		expect(built.shift).ToBe(appended.shift)
+		expect(len(built.tail)).ToBe(len(appended.tail))
+		expect(built.Equals(appended)).ToBe(true)
+	}
+}
+
+func TestNewVectorCopiesValues(t *testing.T) {
+	expect := expectFor(t)
+	values := []interface{}{1, 2, 3}
+	vector := NewVector(values...)
+	values[0] = 4
+	expect(vector.Get(0)).ToBe(1)
+}
+
+func TestVectorUpdate(t *testing.T) {
+	model := rangeSlice(3000)
+	original := NewVector(model...)
+	vector := original
+	random := fakerFor(t)
+	updated := make([]interface{}, len(model))
+	copy(updated, model)
+	for i := 0; i < 500; i++ {
+		index := random.rand.Intn(len(model))
+		vector = vector.Update(index, -i).(*Vector)
+		updated[index] = -i
+	}
+	expectSequenceMatches(t, vector, updated)
+	expectSequenceMatches(t, original, model)
+}
+
+func TestVectorPop(t *testing.T) {
+	expect := expectFor(t)
+	model := rangeSlice(largeVectorSize)
+	vector := NewVector(model...)
+	for len(model) > 0 {
+		last, _ := vector.Peek()
+		popped, value, found := vector.Pop()
+		expect(found).ToBe(true)
+		expect(value).ToBe(model[len(model)-1])
+		expect(last).ToBe(value)
+		model = model[:len(model)-1]
+		vector = popped
+		if len(model)%1000 == 0 || len(model) < 70 {
+			expectSequenceMatches(t, vector, model)
+		}
+	}
+	expect(vector.Size()).ToBe(0)
+	expect(vector.shift).ToBe(vectorBits)
+}
+
+func TestVectorPopThenAppend(t *testing.T) {
+	vector := NewVector(rangeSlice(32*32 + 33)...)
+	popped, _, _ := vector.Pop()
+	popped, _, _ = popped.Pop()
+	first := popped.append("first")
+	second := popped.append("second")
+	expectFor(t)(first.Get(32*32 + 31)).ToBe("first")
+	expectFor(t)(second.Get(32*32 + 31)).ToBe("second")
+	expectSequenceMatches(t, vector, rangeSlice(32*32+33))
+}
+
+func TestVectorPrepend(t *testing.T) {
+	vector := NewVector()
+	model := []interface{}{}
+	for i := 0; i < 2000; i++ {
+		vector = vector.Prepend(i).(*Vector)
+		model = append([]interface{}{i}, model...)
+	}
+	expectVectorInvariants(t, vector)
+	expectSequenceMatches(t, vector, model)
+}
+
+func TestVectorIndexOutOfRange(t *testing.T) {
+	expect := expectFor(t)
+	vector := NewVector(1, 2, 3)
+	expect(func() { vector.Get(3) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { vector.Get(-1) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { vector.Update(3, 0) }).ToPanicWith(ErrIndexOutOfRange)
+	expect(func() { vector.Update(-1, 0) }).ToPanicWith(ErrIndexOutOfRange)
+}
+
+func TestVectorIteratorOutOfRange(t *testing.T) {
+	expect := expectFor(t)
+	iterator := NewVector(1).Iterator()
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+	expect(iterator.MoveNext()).ToBe(true)
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(iterator.MoveNext()).ToBe(false)
+	expect(func() { iterator.Current() }).ToPanicWith(ErrIterationOutOfRange)
+}
+
+func TestVectorEqualsOtherSequences(t *testing.T) {
+	expect := expectFor(t)
+	vector := NewVector(1, 2, 3)
+	expect(vector.Equals(NewSliceSequence(1, 2, 3))).ToBe(true)
+	expect(vector.Hash()).ToBe(NewSliceSequence(1, 2, 3).Hash())
+	expect(vector.Equals(NewVector(1, 2))).ToBe(false)
+}
+
+func TestToVector(t *testing.T) {
+	expect := expectFor(t)
+	expect(NewRange(0, 100).ToVector().ToSlice()).ToDeepEqual(rangeSlice(100))
+	expect(NewSliceSequence(1, 2, 3).Map(func(v interface{}) interface{} {
+		return v.(int) * 2
+	}).ToVector().ToSlice()).ToDeepEqual([]interface{}{2, 4, 6})
+	expect(NewStack().Push(1).Push(2).ToVector().ToSlice()).ToDeepEqual([]interface{}{2, 1})
+	expect(NewStack().ToVector().Size()).ToBe(0)
+	vector := NewVector(1, 2)
+	expect(vector.ToVector()).ToBe(vector)
+}